@@ -0,0 +1,39 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// API の OpenAPI 3.1 のドキュメント
+// ルートを追加・変更した場合は openapi.json も更新すること (openapi_test.go で未記載のルートを検出する)
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openapi.json を読み込んで表示するドキュメントのページ
+// 外部のファイルを読み込まずに表示できるよう、スタイルとスクリプトを HTML 内に記載している
+//
+//go:embed docs.html
+var docsPage []byte
+
+// RegisterDocsRoutes は OpenAPI のドキュメントとドキュメントのページのルートを登録する
+// APIキー認証の対象外とするため、認証ミドルウェアを使用しないルーターに登録する
+func RegisterDocsRoutes(router *mux.Router) {
+	router.HandleFunc("/openapi.json", serveOpenAPI).Methods("GET")
+	router.HandleFunc("/docs", serveDocs).Methods("GET")
+}
+
+// serveOpenAPI は OpenAPI のドキュメントを返すハンドラー
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// serveDocs はドキュメントのページを返すハンドラー
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// api.RegisterRoutes 以外で登録しているルート
+var routesOutsideAPI = map[string]bool{
+	"GET /metrics": true,
+}
+
+// openAPISpec はテストで使用する OpenAPI のドキュメントの一部
+type openAPISpec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas struct {
+			ErrorCode struct {
+				OneOf []struct {
+					Const string `json:"const"`
+				} `json:"oneOf"`
+			} `json:"ErrorCode"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPISpec(t *testing.T) openAPISpec {
+	t.Helper()
+	var spec openAPISpec
+	if err := json.Unmarshal(openAPIDocument, &spec); err != nil {
+		t.Fatalf("openapi.json の読み込みに失敗しました: %v", err)
+	}
+	return spec
+}
+
+// "/books/{id:[0-9]+}" のような mux のパスのテンプレートを OpenAPI の形式 ("/books/{id}") にする
+// 正規表現の中の {} (例: [0-9]{4}) も考慮する
+func openAPIPath(template string) string {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '{' {
+			b.WriteByte(template[i])
+			continue
+		}
+		depth, nameEnd := 1, -1
+		j := i + 1
+		for ; j < len(template) && depth > 0; j++ {
+			switch template[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case ':':
+				if depth == 1 && nameEnd < 0 {
+					nameEnd = j
+				}
+			}
+		}
+		if nameEnd < 0 {
+			nameEnd = j - 1
+		}
+		b.WriteString("{" + template[i+1:nameEnd] + "}")
+		i = j - 1
+	}
+	return b.String()
+}
+
+// 登録したルートを "<メソッド> <パス>" の形式で返す
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	router := mux.NewRouter()
+	RegisterRoutes(router, nil)
+	RegisterDocsRoutes(router)
+
+	var routes []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			// サブルーターのようにメソッドを指定していないルートは対象外
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes = append(routes, method+" "+openAPIPath(template))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ルートの取得に失敗しました: %v", err)
+	}
+	return routes
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := map[string]string{
+		"/books":                             "/books",
+		"/books/{id:[0-9]+}/status":          "/books/{id}/status",
+		"/budgets/{month:[0-9]{4}-[0-9]{2}}": "/budgets/{month}",
+		"/tags/{id}/merge":                   "/tags/{id}/merge",
+		"/admin/audit-log":                   "/admin/audit-log",
+	}
+	for template, want := range tests {
+		if got := openAPIPath(template); got != want {
+			t.Errorf("openAPIPath(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+// 登録したすべてのルートが OpenAPI のドキュメントに記載されていることを確認する
+func TestOpenAPICoversRoutes(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	registered := map[string]bool{}
+	for _, route := range registeredRoutes(t) {
+		registered[route] = true
+		method, path, _ := strings.Cut(route, " ")
+		if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("ルート %s が openapi.json に記載されていません", route)
+		}
+	}
+
+	// 削除したルートがドキュメントに残っていないことも確認する
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			route := strings.ToUpper(method) + " " + path
+			if !registered[route] && !routesOutsideAPI[route] {
+				t.Errorf("openapi.json の %s は登録されていないルートです", route)
+			}
+		}
+	}
+}
+
+// internal/error で定義しているすべてのエラーコードが OpenAPI のドキュメントに記載されていることを確認する
+func TestOpenAPIErrorCodes(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	src, err := os.ReadFile("../internal/error/custom_error.go")
+	if err != nil {
+		t.Fatalf("エラーの定義の読み込みに失敗しました: %v", err)
+	}
+	defined := map[string]bool{}
+	for _, code := range regexp.MustCompile(`"([A-Z]+-ERR-[0-9]{3}-[0-9]{2})"`).FindAllStringSubmatch(string(src), -1) {
+		defined[code[1]] = true
+	}
+
+	documented := map[string]bool{}
+	for _, c := range spec.Components.Schemas.ErrorCode.OneOf {
+		documented[c.Const] = true
+	}
+
+	var missing, unknown []string
+	for code := range defined {
+		if !documented[code] {
+			missing = append(missing, code)
+		}
+	}
+	for code := range documented {
+		if !defined[code] {
+			unknown = append(unknown, code)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unknown)
+	if len(missing) > 0 {
+		t.Errorf("エラーコードが openapi.json に記載されていません: %v", missing)
+	}
+	if len(unknown) > 0 {
+		t.Errorf("openapi.json に定義されていないエラーコードがあります: %v", unknown)
+	}
+}
@@ -4,12 +4,48 @@ import (
 	"database/sql"
 
 	controller "github.com/HwaI12/go-api-tutorial/internal/controller"
+	"github.com/HwaI12/go-api-tutorial/internal/middleware"
 	"github.com/gorilla/mux"
 )
 
 func RegisterRoutes(router *mux.Router, db *sql.DB) {
 	bookController := controller.NewBookController(db)
+	authorController := controller.NewAuthorController(db)
+	tagController := controller.NewTagController(db)
+	budgetController := controller.NewBudgetController(db)
+	auditController := controller.NewAuditController(db)
 
 	router.HandleFunc("/books", bookController.CreateBook).Methods("POST")
 	router.HandleFunc("/books", bookController.GetBooks).Methods("GET")
+	router.HandleFunc("/books/batch", bookController.Batch).Methods("POST")
+	// 取り込みは1件ずつコミットするため、リクエスト全体のトランザクションでは処理しない
+	middleware.WithoutUnitOfWork(router.HandleFunc("/books/import", bookController.ImportBooks).Methods("POST"))
+	// エクスポートは format パラメータで形式を決めるため、Accept ヘッダーによる形式の選択を行わない
+	middleware.WithoutNegotiation(router.HandleFunc("/books/export", bookController.ExportBooks).Methods("GET"))
+	router.HandleFunc("/books/{id:[0-9]+}", bookController.GetBook).Methods("GET")
+	router.HandleFunc("/books/{id:[0-9]+}", bookController.UpdateBook).Methods("PUT")
+	router.HandleFunc("/books/{id:[0-9]+}", bookController.DeleteBook).Methods("DELETE")
+	router.HandleFunc("/books/{id:[0-9]+}/restore", bookController.RestoreBook).Methods("POST")
+	router.HandleFunc("/books/{id:[0-9]+}/status", bookController.ChangeStatus).Methods("POST")
+	router.HandleFunc("/books/{id:[0-9]+}/price-history", bookController.GetPriceHistory).Methods("GET")
+	router.HandleFunc("/books/stats", bookController.GetBookStats).Methods("GET")
+	router.HandleFunc("/books/summary", bookController.GetPriceSummary).Methods("GET")
+
+	router.HandleFunc("/authors", authorController.CreateAuthor).Methods("POST")
+	router.HandleFunc("/authors", authorController.GetAuthors).Methods("GET")
+	router.HandleFunc("/authors/{id:[0-9]+}", authorController.GetAuthor).Methods("GET")
+	router.HandleFunc("/authors/{id:[0-9]+}", authorController.UpdateAuthor).Methods("PUT")
+	router.HandleFunc("/authors/{id:[0-9]+}", authorController.DeleteAuthor).Methods("DELETE")
+
+	router.HandleFunc("/tags", tagController.GetTags).Methods("GET")
+	router.HandleFunc("/tags/{id:[0-9]+}", tagController.RenameTag).Methods("PUT")
+	router.HandleFunc("/tags/{id:[0-9]+}/merge", tagController.MergeTags).Methods("POST")
+
+	router.HandleFunc("/budgets/{month:[0-9]{4}-[0-9]{2}}", budgetController.GetBudget).Methods("GET")
+	router.HandleFunc("/budgets/{month:[0-9]{4}-[0-9]{2}}", budgetController.PutBudget).Methods("PUT")
+
+	// 管理者のみ利用できるルート
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.AdminOnlyMiddleware)
+	adminRouter.HandleFunc("/audit-log", auditController.GetAuditLog).Methods("GET")
 }
@@ -0,0 +1,272 @@
+// bookctl は書籍管理APIの運用コマンド
+//
+//	bookctl audit-export [flags]  監査ログを NDJSON または CSV で出力する
+//	bookctl import [flags] <file>  CSV・JSON・NDJSON のファイルから本を登録する
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	"github.com/HwaI12/go-api-tutorial/internal/importer"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/model"
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+	"github.com/HwaI12/go-api-tutorial/internal/validation"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	logger.InitializeLogger()
+	ctx := transaction.NewTransaction(context.Background())
+
+	var err error
+	switch os.Args[1] {
+	case "audit-export":
+		err = auditExport(ctx, os.Args[2:])
+	case "import":
+		err = importBooks(ctx, os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "不明なコマンドです: %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// .env ファイルを読み込み、サーバーと同じ日時フォーマット・検証ルールを設定してからデータベースに接続する
+func connectDatabase(ctx context.Context) (*sql.DB, error) {
+	if err := godotenv.Load(); err != nil {
+		return nil, fmt.Errorf(".envファイルの読み込みに失敗しました: %v", err)
+	}
+	if err := view.InitializeTimeFormat(); err != nil {
+		return nil, fmt.Errorf("日時フォーマットの初期化に失敗しました: %v", err)
+	}
+	if err := validation.InitializeRules(); err != nil {
+		return nil, fmt.Errorf("検証ルールの読み込みに失敗しました: %v", err)
+	}
+	cfg := config.Database()
+	cfg.Logger = logger.DatabaseLogger{}
+	return database.Connect(ctx, cfg)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "使い方: bookctl <コマンド> [オプション]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "コマンド:")
+	fmt.Fprintln(os.Stderr, "  audit-export  監査ログを NDJSON または CSV で出力する")
+	fmt.Fprintln(os.Stderr, "  import        CSV・JSON・NDJSON のファイルから本を登録する")
+}
+
+// 監査ログを古い順に出力する
+func auditExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("audit-export", flag.ExitOnError)
+	format := fs.String("format", "ndjson", "出力形式 (ndjson / csv)")
+	out := fs.String("out", "", "出力先のファイル (未指定の場合は標準出力)")
+	entity := fs.String("entity", "", "対象の種類で絞り込む (book・tag・author のいずれか)")
+	entityID := fs.String("entity-id", "", "対象のIDで絞り込む")
+	actor := fs.String("actor", "", "呼び出し元で絞り込む")
+	action := fs.String("action", "", "操作で絞り込む (create / update / delete / restore / purge / merge)")
+	from := fs.String("from", "", "この日時以降の監査ログのみ出力する (RFC 3339)")
+	to := fs.String("to", "", "この日時より前の監査ログのみ出力する (RFC 3339)")
+	fs.Parse(args)
+
+	if *format != "ndjson" && *format != "csv" {
+		return fmt.Errorf("出力形式が不正です: %s", *format)
+	}
+	if *action != "" && !model.IsValidAuditAction(*action) {
+		return fmt.Errorf("操作が不正です: %s", *action)
+	}
+	filter := model.AuditFilter{
+		Entity:   *entity,
+		EntityID: *entityID,
+		Actor:    *actor,
+		Action:   *action,
+	}
+	for name, v := range map[string]struct {
+		value string
+		dest  *time.Time
+	}{"from": {*from, &filter.From}, "to": {*to, &filter.To}} {
+		if v.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v.value)
+		if err != nil {
+			return fmt.Errorf("-%s の日時が不正です: %v", name, err)
+		}
+		*v.dest = t
+	}
+
+	db, err := connectDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("出力先のファイルの作成に失敗しました: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// 監査ログはすべてを読み込まずに、取得した分から順に出力する
+	write, flush := newAuditNDJSONWriter(w)
+	if *format == "csv" {
+		write, flush = newAuditCSVWriter(w)
+	}
+	count, err := model.EachAuditEntry(ctx, db, filter, write)
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return fmt.Errorf("監査ログの出力に失敗しました (%d件出力済み): %v", count, err)
+	}
+	fmt.Fprintf(os.Stderr, "監査ログを%d件出力しました\n", count)
+	return nil
+}
+
+// 監査ログを1行に1件の JSON で出力する関数と、出力の最後に呼び出す関数を返す
+func newAuditNDJSONWriter(w io.Writer) (func(model.AuditEntry) error, func() error) {
+	enc := json.NewEncoder(w)
+	write := func(e model.AuditEntry) error {
+		return enc.Encode(e)
+	}
+	return write, func() error { return nil }
+}
+
+// 監査ログを CSV で1行ずつ出力する関数と、出力の最後に呼び出す関数を返す。差分は JSON の文字列として出力する
+func newAuditCSVWriter(w io.Writer) (func(model.AuditEntry) error, func() error) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "actor", "trn_id", "route", "action", "entity", "entity_id", "diff", "created_at"})
+	write := func(e model.AuditEntry) error {
+		return cw.Write([]string{e.ID, e.Actor, e.TrnID, e.Route, e.Action, e.Entity, e.EntityID,
+			string(e.Diff), e.CreatedAt.UTC().Format(time.RFC3339)})
+	}
+	flush := func() error {
+		cw.Flush()
+		return cw.Error()
+	}
+	return write, flush
+}
+
+// ファイルから本を1件ずつ登録し、失敗した行を行番号とエラーコードとともに出力する
+// 失敗した行がある場合は終了コード 1 で終了する
+func importBooks(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "ファイルの形式 (csv / json / ndjson、未指定の場合は拡張子で判断する)")
+	mapSpec := fs.String("map", "", "CSV の列名と項目名の対応 (例: 書名:name,価格:price)")
+	dryRun := fs.Bool("dry-run", false, "登録せず、登録される内容と失敗する行のみ出力する")
+	reportPath := fs.String("report", "", "結果を JSON で出力するファイル")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("取り込むファイルを1つ指定してください (標準入力の場合は -)")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if *format == "jsonl" {
+			*format = importer.FormatNDJSON
+		}
+	}
+	if !importer.IsValidFormat(*format) {
+		return fmt.Errorf("ファイルの形式が不正です: %q (-format で csv / json / ndjson を指定してください)", *format)
+	}
+	mapping, err := importer.ParseMapping(*mapSpec)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("ファイルを開けません: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	reader, err := importer.NewReader(in, *format, mapping)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, importErr := importer.Import(ctx, db, reader, importer.Options{DryRun: *dryRun})
+	for _, row := range report.Rows {
+		if row.Result == importer.RowFailed {
+			fmt.Fprintf(os.Stderr, "%d行目: [%s] %s\n", row.Line, row.ErrorCode, row.ErrorMessage)
+		} else {
+			fmt.Fprintf(os.Stdout, "%d行目: 登録予定 name=%s price=%d isbn=%s\n", row.Line, row.Name, *row.Price, row.ISBN)
+		}
+	}
+	if report.RowsTruncated {
+		fmt.Fprintf(os.Stderr, "行の結果が多いため、%d件目以降の行の出力を省略しました\n", len(report.Rows)+1)
+	}
+	if *reportPath != "" {
+		if err := writeImportReport(*reportPath, report); err != nil {
+			return err
+		}
+	}
+
+	verb := "登録しました"
+	if *dryRun {
+		verb = "登録できます (ドライラン)"
+	}
+	fmt.Fprintf(os.Stderr, "%d件中%d件を%s。失敗: %d件\n", report.Total, report.Imported, verb, report.Failed)
+	if importErr != nil {
+		return fmt.Errorf("%d件目の後で読み込みを中断しました: %v", report.Total, importErr)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d件の取り込みに失敗しました", report.Failed)
+	}
+	return nil
+}
+
+// 取り込みの結果を JSON でファイルに出力する
+func writeImportReport(path string, report *importer.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("結果のファイルの作成に失敗しました: %v", err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("結果の出力に失敗しました: %v", err)
+	}
+	return nil
+}
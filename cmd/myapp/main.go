@@ -12,9 +12,15 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/HwaI12/go-api-tutorial/api"
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	"github.com/HwaI12/go-api-tutorial/internal/job"
 	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
 	"github.com/HwaI12/go-api-tutorial/internal/middleware"
+	"github.com/HwaI12/go-api-tutorial/internal/tracing"
 	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+	"github.com/HwaI12/go-api-tutorial/internal/validation"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
 	"github.com/HwaI12/go-api-tutorial/pkg/database"
 )
 
@@ -40,24 +46,74 @@ func main() {
 		entry.Info(".envファイルの読み込みに成功しました")
 	}
 
+	entry.Info("日時フォーマットを初期化します")
+	if err := view.InitializeTimeFormat(); err != nil {
+		entry.WithError(err).Error("日時フォーマットの初期化に失敗しました。UTCを使用します")
+	}
+
+	entry.Info("検証ルールを読み込みます")
+	if err := validation.InitializeRules(); err != nil {
+		entry.WithError(err).Fatal("検証ルールの読み込みに失敗しました")
+	}
+
+	entry.Info("トレースを初期化します")
+	closeTracer, err := tracing.InitializeTracer()
+	if err != nil {
+		entry.WithError(err).Error("トレースの初期化に失敗しました")
+	}
+	defer func() {
+		if err := closeTracer(); err != nil {
+			entry.WithError(err).Error("トレース出力先のクローズに失敗しました")
+		}
+	}()
+
 	entry.Info("データベースに接続します")
-	db, err := database.Connect(ctx)
+	dbConfig := config.Database()
+	dbConfig.Logger = logger.DatabaseLogger{}
+	db, err := database.Connect(ctx, dbConfig)
 	if err != nil {
 		entry.WithError(err).Fatal("データベースへの接続に失敗しました")
 	} else {
 		entry.Info("データベースに接続しました")
 	}
 
+	if config.GetBool("DB_AUTO_MIGRATE", true) {
+		entry.Info("マイグレーションを適用します")
+		if err := database.Migrate(ctx, db); err != nil {
+			entry.WithError(err).Fatal("マイグレーションの適用に失敗しました")
+		}
+		entry.Info("マイグレーションを適用しました")
+	}
+
+	// 論理削除した本の物理削除ジョブはサーバーの停止時に終了する
+	jobCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	job.StartPurgeJob(jobCtx, db)
+
 	entry.Info("ルーティングを設定します")
 	router := mux.NewRouter()
 	router.Use(middleware.TransactionMiddleware) // トランザクションミドルウェアを使用
-	router.Use(middleware.APIKeyAuthMiddleware)  // APIキー認証ミドルウェアを使用
-	api.RegisterRoutes(router, db)
+	router.Use(middleware.TracingMiddleware)     // トレースミドルウェアを使用
+	router.Use(middleware.MetricsMiddleware)     // メトリクス計測ミドルウェアを使用
+	router.Use(middleware.CompressionMiddleware) // Accept-Encoding に応じたレスポンスの圧縮
+
+	// メトリクスはAPIキー認証の対象外とする
+	metrics.RegisterDBStats(metrics.DefaultRegistry, db)
+	router.Handle("/metrics", metrics.Handler(metrics.DefaultRegistry)).Methods("GET")
+	// API のドキュメント (/openapi.json, /docs) もAPIキー認証の対象外とする
+	api.RegisterDocsRoutes(router)
+
+	apiRouter := router.NewRoute().Subrouter()
+	apiRouter.Use(middleware.NegotiationMiddleware)     // Accept ヘッダーによるレスポンスの形式の選択
+	apiRouter.Use(middleware.APIKeyAuthMiddleware)      // APIキー認証ミドルウェアを使用
+	apiRouter.Use(middleware.IdempotencyMiddleware(db)) // Idempotency-Key による再試行の検出
+	apiRouter.Use(middleware.UnitOfWorkMiddleware(db))  // 書き込みリクエストをトランザクションで処理
+	api.RegisterRoutes(apiRouter, db)
 
 	// サーバーシャットダウンの処理
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: router,
+		Handler: middleware.CORSMiddleware(router), // プリフライトリクエストにも応答できるよう、ルーター全体に CORS を設定する
 	}
 
 	// サーバー終了時にログを出力
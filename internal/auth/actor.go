@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+type ctxKey string
+
+const actorKey ctxKey = "actor"
+
+// API_KEY のみで認証した場合の呼び出し元の名前
+const DefaultActor = "default"
+
+// 認証された呼び出し元の名前をコンテキストに設定する
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// コンテキストから認証された呼び出し元の名前を取得する
+// 認証されていない場合は空文字を返す
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// APIキーに対応する呼び出し元の名前を返す
+// 環境変数 API_KEYS に "名前:キー" をカンマ区切りで指定すると、呼び出し元ごとにキーを発行できる
+// API_KEY に一致した場合は DefaultActor を返す
+func LookupActor(apiKey string) (string, bool) {
+	if apiKey == "" {
+		return "", false
+	}
+	for _, pair := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && name != "" && key == apiKey {
+			return name, true
+		}
+	}
+	if expected := os.Getenv("API_KEY"); expected != "" && apiKey == expected {
+		return DefaultActor, true
+	}
+	return "", false
+}
+
+// 呼び出し元が管理者かどうかを判定する
+// 環境変数 ADMIN_ACTORS に管理者とする呼び出し元の名前をカンマ区切りで指定する
+// 指定していない場合は誰も管理者として扱わない
+func IsAdmin(actor string) bool {
+	if actor == "" {
+		return false
+	}
+	for _, name := range strings.Split(os.Getenv("ADMIN_ACTORS"), ",") {
+		if strings.TrimSpace(name) == actor {
+			return true
+		}
+	}
+	return false
+}
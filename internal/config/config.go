@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 環境変数の文字列を取得する。未設定の場合はデフォルト値を返す
+func GetString(key, defaultValue string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// 環境変数を整数として取得する。未設定または不正な場合はデフォルト値を返す
+func GetInt(key string, defaultValue int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		logrus.Warnf("環境変数%sの値'%s'が整数ではないため、デフォルト値%dを使用します", key, v, defaultValue)
+		return defaultValue
+	}
+	return i
+}
+
+// 環境変数を真偽値として取得する。未設定または不正な場合はデフォルト値を返す
+func GetBool(key string, defaultValue bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		logrus.Warnf("環境変数%sの値'%s'が真偽値ではないため、デフォルト値%tを使用します", key, v, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// 環境変数を時間 (例: 5s, 1m30s) として取得する。未設定または不正な場合はデフォルト値を返す
+func GetDuration(key string, defaultValue time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logrus.Warnf("環境変数%sの値'%s'が時間として不正なため、デフォルト値%sを使用します", key, v, defaultValue)
+		return defaultValue
+	}
+	return d
+}
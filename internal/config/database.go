@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// Database は環境変数からデータベースの設定を読み込む
+// 未設定の項目は database.DefaultConfig の値を使用する
+func Database() database.Config {
+	cfg := database.DefaultConfig()
+	cfg.User = os.Getenv("DB_USER")
+	cfg.Password = os.Getenv("DB_PASSWORD")
+	cfg.Name = os.Getenv("DB_NAME")
+	cfg.Host = os.Getenv("DB_HOST")
+	cfg.Port = os.Getenv("DB_PORT")
+
+	cfg.ParseTime = GetBool("DB_PARSE_TIME", cfg.ParseTime)
+	cfg.Charset = GetString("DB_CHARSET", cfg.Charset)
+	cfg.Collation = GetString("DB_COLLATION", cfg.Collation)
+	cfg.TLS = GetString("DB_TLS", cfg.TLS)
+	cfg.ConnectTimeout = GetDuration("DB_CONNECT_TIMEOUT", cfg.ConnectTimeout)
+	cfg.ReadTimeout = GetDuration("DB_READ_TIMEOUT", cfg.ReadTimeout)
+	cfg.WriteTimeout = GetDuration("DB_WRITE_TIMEOUT", cfg.WriteTimeout)
+
+	cfg.MaxOpenConns = GetInt("DB_MAX_OPEN_CONNS", cfg.MaxOpenConns)
+	cfg.MaxIdleConns = GetInt("DB_MAX_IDLE_CONNS", cfg.MaxIdleConns)
+	cfg.ConnMaxLifetime = GetDuration("DB_CONN_MAX_LIFETIME", cfg.ConnMaxLifetime)
+	cfg.ConnMaxIdleTime = GetDuration("DB_CONN_MAX_IDLE_TIME", cfg.ConnMaxIdleTime)
+
+	cfg.PingRetries = GetInt("DB_PING_RETRIES", cfg.PingRetries)
+	cfg.PingBackoff = GetDuration("DB_PING_BACKOFF", cfg.PingBackoff)
+	cfg.PingMaxBackoff = GetDuration("DB_PING_MAX_BACKOFF", cfg.PingMaxBackoff)
+
+	cfg.DeadlockRetries = GetInt("DB_DEADLOCK_RETRIES", cfg.DeadlockRetries)
+	cfg.DeadlockBackoff = GetDuration("DB_DEADLOCK_BACKOFF", cfg.DeadlockBackoff)
+
+	cfg.QueryTimeout = GetDuration("DB_QUERY_TIMEOUT", cfg.QueryTimeout)
+	cfg.OperationTimeouts = operationTimeouts(os.Environ())
+	return cfg
+}
+
+// DB_TIMEOUT_<操作名> (例: DB_TIMEOUT_GET_BOOKS) の環境変数から、操作ごとのタイムアウトを読み込む
+func operationTimeouts(environ []string) map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		operation, ok := strings.CutPrefix(key, "DB_TIMEOUT_")
+		if !ok || operation == "" || value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			logrus.Warnf("環境変数%sの値'%s'が時間として不正なため、無視します", key, value)
+			continue
+		}
+		timeouts[strings.ToLower(operation)] = d
+	}
+	return timeouts
+}
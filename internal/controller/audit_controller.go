@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+)
+
+// 監査ログの1ページあたりの件数のデフォルト値と最大値
+const (
+	defaultAuditPerPage = 50
+	maxAuditPerPage     = 200
+)
+
+// 監査ログに関する操作を行うコントローラー
+type AuditController struct {
+	DB *sql.DB
+}
+
+// 新しい AuditController を作成して返す
+func NewAuditController(db *sql.DB) *AuditController {
+	return &AuditController{DB: db}
+}
+
+// GetAuditLog は監査ログを新しい順にページ単位で返すハンドラー
+func (c *AuditController) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	filter, page, perPage, userErr := parseAuditFilter(r)
+	if userErr != nil {
+		entry.Errorf("クエリパラメータが不正です: %v", userErr)
+		view.RespondWithError(w, ctx, userErr)
+		return
+	}
+
+	entry.Infof("監査ログの取得を開始します: page=%d, per_page=%d", page, perPage)
+	entries, total, err := model.GetAuditLog(ctx, c.DB, filter)
+	if err != nil {
+		entry.Errorf("監査ログの取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("監査ログの取得に成功しました")
+
+	entryList := make([]map[string]interface{}, len(entries))
+	for i, e := range entries {
+		entryList[i] = auditEntryToMap(e)
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, map[string]interface{}{
+		"entries":  entryList,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	})
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// クエリパラメータから監査ログの絞り込み条件とページを取得する
+func parseAuditFilter(r *http.Request) (model.AuditFilter, int, int, *errors.UserDefinedError) {
+	query := r.URL.Query()
+	filter := model.AuditFilter{
+		Entity: query.Get("entity"),
+		Actor:  query.Get("actor"),
+		TrnID:  query.Get("trn_id"),
+	}
+
+	if entityID := query.Get("entity_id"); entityID != "" {
+		if !isID(entityID) {
+			return filter, 0, 0, errors.InvalidQueryParamError("entity_id")
+		}
+		filter.EntityID = entityID
+	}
+	if action := query.Get("action"); action != "" {
+		if !model.IsValidAuditAction(action) {
+			return filter, 0, 0, errors.InvalidQueryParamError("action")
+		}
+		filter.Action = action
+	}
+	for name, dest := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, 0, 0, errors.InvalidQueryParamError(name)
+			}
+			*dest = t
+		}
+	}
+
+	page, perPage := 1, defaultAuditPerPage
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return filter, 0, 0, errors.InvalidQueryParamError("page")
+		}
+		page = n
+	}
+	if v := query.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditPerPage {
+			return filter, 0, 0, errors.InvalidQueryParamError("per_page")
+		}
+		perPage = n
+	}
+	filter.Limit = perPage
+	filter.Offset = (page - 1) * perPage
+	return filter, page, perPage, nil
+}
+
+// 監査ログをレスポンス用のデータに変換する
+func auditEntryToMap(e model.AuditEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         e.ID,
+		"actor":      e.Actor,
+		"trn_id":     e.TrnID,
+		"route":      e.Route,
+		"action":     e.Action,
+		"entity":     e.Entity,
+		"entity_id":  e.EntityID,
+		"diff":       e.Diff,
+		"created_at": view.FormatTime(e.CreatedAt),
+	}
+}
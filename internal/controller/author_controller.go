@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/gorilla/mux"
+)
+
+// 著者データに関する操作を行うコントローラー
+type AuthorController struct {
+	DB *sql.DB
+}
+
+// 新しい AuthorController を作成して返す
+func NewAuthorController(db *sql.DB) *AuthorController {
+	return &AuthorController{DB: db}
+}
+
+// 新しい著者データをデータベースに登録するハンドラー
+func (c *AuthorController) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	var input model.AuthorInput
+	entry.Infof("リクエストボディのデコードを開始します")
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	if input.Name == nil {
+		entry.Errorf("パラメータ'name'がありません")
+		view.RespondWithError(w, ctx, errors.ParamNameMissingError())
+		return
+	}
+
+	author := model.Author{Name: *input.Name}
+	if err := author.Validate(ctx); err != nil {
+		entry.Errorf("バリデーションに失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	entry.Infof("著者の登録を開始します")
+	if err := author.CreateAuthor(ctx, c.DB); err != nil {
+		entry.Errorf("著者の登録に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("著者の登録に成功しました")
+
+	view.RespondWithJSON(w, ctx, http.StatusCreated, newAuthorResponse(author))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// GetAuthors は著者の一覧を返すハンドラー
+func (c *AuthorController) GetAuthors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	entry.Infof("著者の一覧取得を開始します")
+	authors, err := model.GetAuthors(ctx, c.DB)
+	if err != nil {
+		entry.Errorf("著者の一覧取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("著者の一覧取得に成功しました")
+
+	authorList := make([]view.AuthorResponse, len(authors))
+	for i, author := range authors {
+		authorList[i] = newAuthorResponse(author)
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, map[string]interface{}{
+		"authors": authorList,
+	})
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// GetAuthor は指定したIDの著者を返すハンドラー
+func (c *AuthorController) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	author, err := model.GetAuthor(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("著者の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	view.RespondWithJSON(w, ctx, http.StatusOK, newAuthorResponse(*author))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// UpdateAuthor は指定したIDの著者の名前を更新するハンドラー
+func (c *AuthorController) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	var input model.AuthorInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	if input.Name == nil {
+		entry.Errorf("パラメータ'name'がありません")
+		view.RespondWithError(w, ctx, errors.ParamNameMissingError())
+		return
+	}
+
+	author, err := model.GetAuthor(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("更新する著者の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	author.Name = *input.Name
+	if err := author.Validate(ctx); err != nil {
+		entry.Errorf("バリデーションに失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	entry.Infof("著者の更新を開始します")
+	if err := author.UpdateAuthor(ctx, c.DB); err != nil {
+		entry.Errorf("著者の更新に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("著者の更新に成功しました")
+
+	view.RespondWithJSON(w, ctx, http.StatusOK, newAuthorResponse(*author))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// DeleteAuthor は指定したIDの著者を削除するハンドラー
+func (c *AuthorController) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	entry.Infof("著者の削除を開始します: id=%s", id)
+	if err := model.DeleteAuthor(ctx, c.DB, id); err != nil {
+		entry.Errorf("著者の削除に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("著者の削除に成功しました")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 著者をレスポンス用のデータに変換する
+func newAuthorResponse(author model.Author) view.AuthorResponse {
+	return view.AuthorResponse{
+		ID:        author.ID,
+		Name:      author.Name,
+		CreatedAt: view.FormatTime(author.CreatedAt),
+	}
+}
@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// 1回のバッチで実行できる操作の数のデフォルト値
+const defaultBatchMaxSize = 500
+
+// 操作ごとに作成するセーブポイントの名前
+const batchSavepoint = "book_batch_item"
+
+// atomic のバッチで失敗した操作があったため、トランザクションをロールバックすることを表すエラー
+var errBatchFailed = stderrors.New("バッチ内の操作が失敗したためロールバックします")
+
+// batchResult はバッチ内の1件の操作の結果
+type batchResult struct {
+	op     string
+	id     string
+	status int
+	book   *model.Book
+	err    *errors.UserDefinedError
+}
+
+// Batch は本の登録・更新・削除をまとめて実行するハンドラー
+// mode が atomic (デフォルト) の場合は1件でも失敗するとすべての操作を取り消し、
+// best_effort の場合は成功した操作のみ反映する。操作ごとの結果を1つのレスポンスで返す
+func (c *BookController) Batch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	var input model.BookBatchInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+
+	mode := model.BatchModeAtomic
+	if input.Mode != nil {
+		if !model.IsValidBatchMode(*input.Mode) {
+			entry.Errorf("パラメータ'mode'の値が不正です: %s", *input.Mode)
+			view.RespondWithError(w, ctx, errors.InvalidBatchModeError())
+			return
+		}
+		mode = *input.Mode
+	}
+	if input.Operations == nil {
+		entry.Errorf("パラメータ'operations'がありません")
+		view.RespondWithError(w, ctx, errors.ParamMissingError("operations"))
+		return
+	}
+	maxSize := config.GetInt("BATCH_MAX_SIZE", defaultBatchMaxSize)
+	if maxSize > 0 && len(input.Operations) > maxSize {
+		entry.Errorf("バッチの操作が多すぎます: %d件 (上限: %d件)", len(input.Operations), maxSize)
+		view.RespondWithError(w, ctx, errors.BatchTooLargeError(maxSize))
+		return
+	}
+
+	entry.Infof("バッチの実行を開始します: mode=%s, %d件", mode, len(input.Operations))
+	var results []batchResult
+	failed := 0
+	// Unit of Work のトランザクションがあればそれに参加し、なければここでトランザクションを開始する
+	err := database.RunInTx(ctx, c.DB, func(ctx context.Context) error {
+		results = make([]batchResult, 0, len(input.Operations))
+		failed = 0
+		for i, op := range input.Operations {
+			var result batchResult
+			err := database.Savepoint(ctx, c.DB, batchSavepoint, func(ctx context.Context) error {
+				result = c.runBatchOperation(ctx, op)
+				if result.err != nil {
+					return result.err
+				}
+				return nil
+			})
+			if database.TxAborted(ctx) {
+				// デッドロックでトランザクション全体が失われたため、以降の操作は実行しない
+				return err
+			}
+			if err != nil {
+				userErr, ok := err.(*errors.UserDefinedError)
+				if !ok || errors.IsRequestCanceled(userErr) {
+					return err
+				}
+				entry.Warnf("バッチの%d件目の操作に失敗しました: %v", i, userErr)
+				failed++
+			}
+			results = append(results, result)
+		}
+		if mode == model.BatchModeAtomic && failed > 0 {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if err != nil && err != errBatchFailed {
+		entry.Errorf("バッチの実行に失敗しました: %v", err)
+		if _, ok := err.(*errors.UserDefinedError); !ok {
+			err = errors.DatabaseTransactionError()
+		}
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	committed := err == nil
+	status := http.StatusOK
+	if !committed {
+		// 失敗した操作以外は取り消されたことを結果に反映する
+		status = http.StatusUnprocessableEntity
+		for i := range results {
+			if results[i].err == nil {
+				results[i].status = http.StatusFailedDependency
+				results[i].book = nil
+				results[i].err = errors.BatchOperationRolledBackError()
+			} else if results[i].status >= http.StatusInternalServerError {
+				status = http.StatusInternalServerError
+			}
+		}
+	}
+
+	created := 0
+	items := make([]map[string]interface{}, len(results))
+	for i, result := range results {
+		if committed && result.op == model.BatchOpCreate && result.err == nil {
+			created++
+		}
+		items[i] = batchResultToMap(i, result)
+	}
+	if created > 0 {
+		// バッチは Unit of Work のトランザクションに参加するため、そのコミット後に数える
+		database.AfterCommit(ctx, func() { metrics.BooksCreatedTotal.Add(float64(created)) })
+	}
+	entry.Infof("バッチの実行が終了しました: mode=%s, 成功=%d件, 失敗=%d件, 反映=%t", mode, len(results)-failed, failed, committed)
+
+	view.RespondWithJSON(w, ctx, status, map[string]interface{}{
+		"mode":      mode,
+		"committed": committed,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   items,
+	})
+}
+
+// runBatchOperation はバッチ内の1件の操作を実行する
+// 単独の POST /books・PUT /books/{id}・DELETE /books/{id} と同じ検証を行う
+func (c *BookController) runBatchOperation(ctx context.Context, op model.BookBatchOperation) batchResult {
+	entry := logger.WithTransaction(ctx)
+	result := batchResult{}
+	if op.Op != nil {
+		result.op = *op.Op
+	}
+	if op.ID != nil {
+		result.id = *op.ID
+	}
+	fail := func(err error) batchResult {
+		userErr, ok := err.(*errors.UserDefinedError)
+		if !ok {
+			entry.Errorf("予期しないエラーが発生しました: %v", err)
+			userErr = errors.UnexpectedError()
+		}
+		result.status = userErr.HTTPStatusCode
+		result.err = userErr
+		return result
+	}
+
+	switch result.op {
+	case model.BatchOpCreate:
+		if op.Book == nil {
+			return fail(errors.ParamMissingError("book"))
+		}
+		book, err := model.NewBookFromInput(ctx, *op.Book)
+		if err != nil {
+			return fail(err)
+		}
+		if err := book.Validate(ctx); err != nil {
+			return fail(err)
+		}
+		if err := book.CreateBook(ctx, c.DB); err != nil {
+			return fail(err)
+		}
+		result.id = book.ID
+		result.status = http.StatusCreated
+
+	case model.BatchOpUpdate:
+		if !isID(result.id) {
+			return fail(errors.ParamMissingError("id"))
+		}
+		if op.Book == nil {
+			return fail(errors.ParamMissingError("book"))
+		}
+		if op.Book.Version == nil {
+			return fail(errors.ParamMissingError("version"))
+		}
+		book, err := model.GetBook(ctx, c.DB, result.id)
+		if err != nil {
+			return fail(err)
+		}
+		book.Version = *op.Book.Version
+		book.ApplyInput(*op.Book)
+		if err := book.Validate(ctx); err != nil {
+			return fail(err)
+		}
+		if err := book.UpdateBook(ctx, c.DB); err != nil {
+			return fail(err)
+		}
+		result.status = http.StatusOK
+
+	case model.BatchOpDelete:
+		if !isID(result.id) {
+			return fail(errors.ParamMissingError("id"))
+		}
+		if err := model.DeleteBook(ctx, c.DB, result.id); err != nil {
+			return fail(err)
+		}
+		result.status = http.StatusNoContent
+		return result
+
+	default:
+		return fail(errors.InvalidBatchOperationError())
+	}
+
+	book, err := model.GetBook(ctx, c.DB, result.id)
+	if err != nil {
+		return fail(err)
+	}
+	result.book = book
+	return result
+}
+
+// バッチ内の1件の操作の結果をレスポンスの形式に変換する
+func batchResultToMap(index int, result batchResult) map[string]interface{} {
+	item := map[string]interface{}{
+		"index":  index,
+		"op":     nullableString(result.op),
+		"id":     nullableString(result.id),
+		"status": result.status,
+	}
+	if result.book != nil {
+		item["book"] = newBookDetailResponse(*result.book)
+	}
+	if result.err != nil {
+		item["error"] = map[string]interface{}{
+			"error_code":    result.err.ErrorCode,
+			"error_message": result.err.ErrorMessage,
+		}
+	}
+	return item
+}
@@ -3,13 +3,20 @@ package controller
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/HwaI12/go-api-tutorial/internal/auth"
 	errors "github.com/HwaI12/go-api-tutorial/internal/error"
 	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
 	model "github.com/HwaI12/go-api-tutorial/internal/model"
 	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+	"github.com/gorilla/mux"
 )
 
 // 書籍データに関する操作を行うコントローラー
@@ -36,24 +43,13 @@ func (c *BookController) CreateBook(w http.ResponseWriter, r *http.Request) {
 	}
 	entry.Infof("リクエストボディのデコードに成功しました")
 
-	// パラメータの存在チェック
-	if input.Name == nil {
-		entry.Errorf("パラメータ'name'がありません")
-		view.RespondWithError(w, ctx, errors.ParamNameMissingError())
-		return
-	}
-	if input.Price == nil {
-		entry.Errorf("パラメータ'price'がありません")
-		view.RespondWithError(w, ctx, errors.ParamPriceMissingError())
+	// パラメータの存在チェックと Book モデルへのマッピング
+	book, err := model.NewBookFromInput(ctx, input)
+	if err != nil {
+		respondWithError(w, ctx, entry, err)
 		return
 	}
 
-	// Book モデルにデータをマッピング
-	book := model.Book{
-		Name:  *input.Name,
-		Price: *input.Price,
-	}
-
 	entry.Debugf("入力されたデータ: %+v", map[string]interface{}{
 		"name":  book.Name,
 		"price": book.Price,
@@ -70,19 +66,27 @@ func (c *BookController) CreateBook(w http.ResponseWriter, r *http.Request) {
 	entry.Infof("本の登録を開始します")
 	if err := book.CreateBook(ctx, c.DB); err != nil {
 		entry.Errorf("本の登録に失敗しました: %v", err)
-		view.RespondWithError(w, ctx, err.(*errors.UserDefinedError))
+		respondWithError(w, ctx, entry, err)
 		return
 	}
 	entry.Infof("本の登録に成功しました")
+	// Unit of Work のトランザクションがロールバック・再試行された場合に数えないよう、コミット後に数える
+	database.AfterCommit(ctx, func() { metrics.BooksCreatedTotal.Inc() })
 
-	entry.Infof("レスポンスを返却します")
-	responseData := map[string]interface{}{
-		"name":  book.Name,
-		"price": book.Price,
+	// 著者・タグを含めた登録後の本を返す
+	created, err := model.GetBook(ctx, c.DB, book.ID)
+	if err != nil {
+		entry.Errorf("登録後の本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
 	}
+
+	entry.Infof("レスポンスを返却します")
+	responseData := view.BookCreatedResponse{BookResponse: newBookDetailResponse(*created)}
 	response := view.CreateResponse(ctx, responseData)
 	entry.Debugf("レスポンス結果: %+v", response)
-	fmt.Printf("レスポンス結果: %+v", response)
+	w.Header().Set("Location", "/books/"+created.ID)
+	view.SetCacheValidators(w, view.VersionedETag(ctx, created.Version, responseData.BookResponse), created.UpdatedAt)
 	view.RespondWithJSON(w, ctx, http.StatusCreated, responseData)
 	entry.Infof("レスポンスの返却に成功しました")
 }
@@ -92,11 +96,18 @@ func (c *BookController) GetBooks(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	entry := logger.WithTransaction(ctx)
 
+	filter, userErr := parseBookFilter(r)
+	if userErr != nil {
+		entry.Errorf("クエリパラメータが不正です: %v", userErr)
+		view.RespondWithError(w, ctx, userErr)
+		return
+	}
+
 	entry.Infof("本の一覧取得を開始します")
-	books, err := model.GetBooks(ctx, c.DB)
+	books, err := model.GetBooks(ctx, c.DB, filter)
 	if err != nil {
 		entry.Errorf("本の一覧取得に失敗しました: %v", err)
-		view.RespondWithError(w, ctx, err.(*errors.UserDefinedError))
+		respondWithError(w, ctx, entry, err)
 		return
 	}
 
@@ -108,22 +119,509 @@ func (c *BookController) GetBooks(w http.ResponseWriter, r *http.Request) {
 	entry.Infof("本の一覧取得に成功しました")
 
 	// データを変換する
-	bookList := make([]map[string]interface{}, len(books))
+	bookList := make([]view.BookResponse, len(books))
 	for i, book := range books {
-		bookList[i] = map[string]interface{}{
-			"id":         book.ID,
-			"name":       book.Name,
-			"price":      book.Price,
-			"created_at": book.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
+		bookList[i] = newBookResponse(book, filter.EmbedAuthors, filter.EmbedTags)
 	}
 
-	entry.Infof("レスポンスを返却します")
-	responseData := map[string]interface{}{
-		"books": bookList,
+	// 一覧は ETag のみで判定する。本の削除や著者・タグの名前の変更では、返却する本の更新日時が変わらないため
+	// Last-Modified (更新日時の最大値) では変更を検出できない
+	responseData := view.BookListResponse{Books: bookList}
+	if view.CheckNotModified(w, r, view.ETag(ctx, responseData), time.Time{}) {
+		entry.Infof("本の一覧は更新されていないため、304を返却しました")
+		return
 	}
+
+	entry.Infof("レスポンスを返却します")
 	response := view.CreateResponse(ctx, responseData)
 	entry.Debugf("レスポンス結果: %+v", response)
 	view.RespondWithJSON(w, ctx, http.StatusOK, responseData)
 	entry.Infof("レスポンスの返却に成功しました")
 }
+
+// GetBook は指定したIDの書籍データを著者とタグの情報を含めて返すハンドラー
+func (c *BookController) GetBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	entry.Infof("本の取得を開始します: id=%s", id)
+	book, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("本の取得に成功しました")
+
+	responseData := newBookDetailResponse(*book)
+	if view.CheckNotModified(w, r, view.VersionedETag(ctx, book.Version, responseData), book.UpdatedAt) {
+		entry.Infof("本は更新されていないため、304を返却しました")
+		return
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, responseData)
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// UpdateBook は指定したIDの書籍データを更新するハンドラー
+// リクエストボディに含まれるパラメータのみ更新する
+func (c *BookController) UpdateBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	var input model.BookInput
+	entry.Infof("リクエストボディのデコードを開始します")
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	entry.Infof("リクエストボディのデコードに成功しました")
+
+	version, fromIfMatch, userErr := expectedVersion(r, input.Version)
+	if userErr != nil {
+		entry.Errorf("更新前のバージョンが不正です: %v", userErr)
+		view.RespondWithError(w, ctx, userErr)
+		return
+	}
+
+	book, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("更新する本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	// 取得した本ではなく、クライアントが指定したバージョンを基準に更新する
+	book.Version = version
+
+	// 指定されたパラメータのみ上書きする
+	book.ApplyInput(input)
+
+	entry.Infof("バリデーションを開始します")
+	if err := book.Validate(ctx); err != nil {
+		entry.Errorf("バリデーションに失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("バリデーションに成功しました")
+
+	entry.Infof("本の更新を開始します")
+	if err := book.UpdateBook(ctx, c.DB); err != nil {
+		entry.Errorf("本の更新に失敗しました: %v", err)
+		// If-Match の条件を満たさない場合は、DELETE・ステータスの変更と同じく 412 を返す
+		// (409 はリクエストボディの version が古い場合のみ)
+		if userErr, ok := err.(*errors.UserDefinedError); ok && fromIfMatch && errors.IsVersionConflict(userErr) {
+			err = errors.PreconditionFailedError()
+		}
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("本の更新に成功しました")
+
+	updated, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("更新後の本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	responseData := newBookDetailResponse(*updated)
+	view.SetCacheValidators(w, view.VersionedETag(ctx, updated.Version, responseData), updated.UpdatedAt)
+	view.RespondWithJSON(w, ctx, http.StatusOK, responseData)
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// ChangeStatus は指定したIDの本の購入ステータスを変更するハンドラー
+// 変更者として認証された呼び出し元を記録する
+func (c *BookController) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	var input model.StatusInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	if input.Status == nil {
+		entry.Errorf("パラメータ'status'がありません")
+		view.RespondWithError(w, ctx, errors.ParamMissingError("status"))
+		return
+	}
+	if !model.IsValidStatus(*input.Status) {
+		entry.Errorf("パラメータ'status'の値が不正です: %s", *input.Status)
+		view.RespondWithError(w, ctx, errors.InvalidStatusError())
+		return
+	}
+	if err := c.checkIfMatch(r, id); err != nil {
+		entry.Errorf("ステータス変更の前提条件を満たしていません: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	book, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	entry.Infof("ステータスの変更を開始します: %s -> %s", book.Status, *input.Status)
+	if err := book.TransitionStatus(ctx, c.DB, *input.Status, auth.ActorFromContext(ctx)); err != nil {
+		entry.Errorf("ステータスの変更に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("ステータスの変更に成功しました")
+
+	updated, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("変更後の本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	responseData := newBookDetailResponse(*updated)
+	view.SetCacheValidators(w, view.VersionedETag(ctx, updated.Version, responseData), updated.UpdatedAt)
+	view.RespondWithJSON(w, ctx, http.StatusOK, responseData)
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// DeleteBook は指定したIDの本を論理削除するハンドラー
+func (c *BookController) DeleteBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	if err := c.checkIfMatch(r, id); err != nil {
+		entry.Errorf("削除の前提条件を満たしていません: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	entry.Infof("本の削除を開始します: id=%s", id)
+	if err := model.DeleteBook(ctx, c.DB, id); err != nil {
+		entry.Errorf("本の削除に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("本の削除に成功しました")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreBook は論理削除した本を復元するハンドラー
+func (c *BookController) RestoreBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	entry.Infof("本の復元を開始します: id=%s", id)
+	if err := model.RestoreBook(ctx, c.DB, id); err != nil {
+		entry.Errorf("本の復元に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("本の復元に成功しました")
+
+	restored, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("復元後の本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	responseData := newBookDetailResponse(*restored)
+	view.SetCacheValidators(w, view.VersionedETag(ctx, restored.Version, responseData), restored.UpdatedAt)
+	view.RespondWithJSON(w, ctx, http.StatusOK, responseData)
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// GetPriceHistory は指定したIDの本の価格の変更履歴を返すハンドラー
+func (c *BookController) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	// 本が存在しない場合は 404 を返す
+	book, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("本の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	entry.Infof("価格の変更履歴の取得を開始します: id=%s", id)
+	history, err := model.GetPriceHistory(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("価格の変更履歴の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("価格の変更履歴の取得に成功しました")
+
+	view.RespondWithJSON(w, ctx, http.StatusOK, newPriceHistoryResponse(*book, history))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// GetBookStats は購入ステータスごとの本の数を返すハンドラー
+func (c *BookController) GetBookStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	entry.Infof("本の統計の取得を開始します")
+	stats, err := model.GetBookStats(ctx, c.DB)
+	if err != nil {
+		entry.Errorf("本の統計の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("本の統計の取得に成功しました")
+
+	view.RespondWithJSON(w, ctx, http.StatusOK, view.BookStatsResponse{Total: stats.Total, ByStatus: stats.ByStatus})
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// GetPriceSummary は本の件数と価格の合計・平均・最小・最大を返すハンドラー
+// クエリパラメータ group_by に status / tag / month を指定するとグループごとに集計する
+func (c *BookController) GetPriceSummary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	filter, userErr := parseBookFilter(r)
+	if userErr != nil {
+		entry.Errorf("クエリパラメータが不正です: %v", userErr)
+		view.RespondWithError(w, ctx, userErr)
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if !model.IsValidGroupBy(groupBy) {
+		entry.Errorf("クエリパラメータ'group_by'が不正です: %s", groupBy)
+		view.RespondWithError(w, ctx, errors.InvalidQueryParamError("group_by"))
+		return
+	}
+
+	entry.Infof("価格の集計を開始します")
+	overall, groups, err := model.GetPriceSummary(ctx, c.DB, filter, groupBy)
+	if err != nil {
+		entry.Errorf("価格の集計に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("価格の集計に成功しました")
+
+	responseData := newPriceSummaryListResponse(overall, groups, groupBy)
+	view.RespondWithJSON(w, ctx, http.StatusOK, responseData)
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// クエリパラメータから本の絞り込み条件を作成する
+func parseBookFilter(r *http.Request) (model.BookFilter, *errors.UserDefinedError) {
+	query := r.URL.Query()
+	filter := model.BookFilter{}
+
+	if authorID := query.Get("author_id"); authorID != "" {
+		if !isID(authorID) {
+			return filter, errors.InvalidQueryParamError("author_id")
+		}
+		filter.AuthorID = authorID
+	}
+
+	if isbn := query.Get("isbn"); isbn != "" {
+		normalized, err := model.NormalizeISBN(isbn)
+		if err != nil {
+			return filter, errors.InvalidQueryParamError("isbn")
+		}
+		filter.ISBN = normalized
+	}
+
+	if status := query.Get("status"); status != "" {
+		if !model.IsValidStatus(status) {
+			return filter, errors.InvalidQueryParamError("status")
+		}
+		filter.Status = status
+	}
+
+	if tags := query.Get("tags"); tags != "" {
+		seen := map[string]bool{}
+		for _, name := range strings.Split(tags, ",") {
+			tag, err := model.NormalizeTagName(r.Context(), name)
+			if err != nil {
+				return filter, errors.InvalidQueryParamError("tags")
+			}
+			// 本に付けるタグと同じく、大文字・小文字の違いは同じタグとみなして1つにまとめる
+			// (tag_mode=all でタグの数と一致する本を検索するため、重複があると一致しなくなる)
+			key := strings.ToLower(tag)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	filter.TagMode = model.TagModeAny
+	if mode := query.Get("tag_mode"); mode != "" {
+		if mode != model.TagModeAny && mode != model.TagModeAll {
+			return filter, errors.InvalidQueryParamError("tag_mode")
+		}
+		filter.TagMode = mode
+	}
+
+	if includeDeleted := query.Get("include_deleted"); includeDeleted != "" {
+		b, err := strconv.ParseBool(includeDeleted)
+		if err != nil {
+			return filter, errors.InvalidQueryParamError("include_deleted")
+		}
+		filter.IncludeDeleted = b
+	}
+
+	if embed := query.Get("embed"); embed != "" {
+		for _, name := range strings.Split(embed, ",") {
+			switch strings.TrimSpace(name) {
+			case "authors":
+				filter.EmbedAuthors = true
+			case "tags":
+				filter.EmbedTags = true
+			default:
+				return filter, errors.InvalidQueryParamError("embed")
+			}
+		}
+	}
+	return filter, nil
+}
+
+// 本をレスポンスの型に変換する
+// withAuthors・withTags が true の場合は著者・タグを含める
+func newBookResponse(book model.Book, withAuthors, withTags bool) view.BookResponse {
+	res := view.BookResponse{
+		ID:              book.ID,
+		Name:            book.Name,
+		Price:           book.Price,
+		ISBN:            optionalString(book.ISBN),
+		Status:          book.Status,
+		StatusChangedAt: optionalTime(book.StatusChangedAt),
+		StatusChangedBy: optionalString(book.StatusChangedBy),
+		CreatedAt:       view.FormatTime(book.CreatedAt),
+		UpdatedAt:       view.FormatTime(book.UpdatedAt),
+		Version:         book.Version,
+		DeletedAt:       optionalTime(book.DeletedAt),
+	}
+	if withAuthors {
+		authors := make([]view.AuthorResponse, len(book.Authors))
+		for i, author := range book.Authors {
+			authors[i] = newAuthorResponse(author)
+		}
+		res.Authors = &authors
+	}
+	if withTags {
+		tags := book.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		res.Tags = &tags
+	}
+	return res
+}
+
+// expectedVersion はリクエストボディの version または If-Match ヘッダーから更新前のバージョンを取得する
+// 両方が指定されている場合は一致している必要がある
+// If-Match ヘッダーでバージョンを指定した場合は fromIfMatch に true を返す
+func expectedVersion(r *http.Request, bodyVersion *int) (version int, fromIfMatch bool, userErr *errors.UserDefinedError) {
+	var headerVersion *int
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		v, ok := view.ETagVersion(ifMatch)
+		if !ok {
+			// バージョンを含まない ETag はどのバージョンの本とも一致しない
+			return 0, true, errors.PreconditionFailedError()
+		}
+		headerVersion = &v
+	}
+
+	switch {
+	case bodyVersion != nil && headerVersion != nil && *bodyVersion != *headerVersion:
+		return 0, true, errors.VersionMismatchError()
+	case headerVersion != nil:
+		return *headerVersion, true, nil
+	case bodyVersion != nil:
+		return *bodyVersion, false, nil
+	}
+	return 0, false, errors.ParamMissingError("version")
+}
+
+// checkIfMatch は If-Match ヘッダーが指定されている場合、本の現在の ETag と一致するかどうかを確認する
+// 一致しない場合は PreconditionFailedError を返す
+// 確認してから更新するまでの間に他のリクエストで変更されないよう、先に本の行をロックする
+func (c *BookController) checkIfMatch(r *http.Request, id string) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+	ctx := r.Context()
+	if err := model.LockBook(ctx, c.DB, id); err != nil {
+		return err
+	}
+	book, err := model.GetBook(ctx, c.DB, id)
+	if err != nil {
+		return err
+	}
+	if !view.MatchesIfMatch(ifMatch, view.VersionedETag(ctx, book.Version, newBookDetailResponse(*book))) {
+		logger.WithTransaction(ctx).Warnf("If-Matchが現在のETagと一致しません: id=%s, If-Match=%s", id, ifMatch)
+		return errors.PreconditionFailedError()
+	}
+	return nil
+}
+
+// 本の詳細をレスポンスの型に変換する
+// 一覧の項目に加えて、著者・タグとこれまでの最安値を含める
+func newBookDetailResponse(book model.Book) view.BookResponse {
+	res := newBookResponse(book, true, true)
+	lowestPrice := book.LowestPrice
+	res.LowestPrice = &lowestPrice
+	return res
+}
+
+// 価格の集計結果をレスポンスの型に変換する
+func newPriceSummaryResponse(summary model.PriceSummary) view.PriceSummaryResponse {
+	return view.PriceSummaryResponse{
+		Count:   summary.Count,
+		Total:   summary.Total,
+		Average: math.Round(summary.Average*100) / 100,
+		Min:     summary.Min,
+		Max:     summary.Max,
+	}
+}
+
+// 全体とグループごとの価格の集計結果をレスポンスの型に変換する
+// groupBy を指定しない場合はグループごとの集計を含めない
+func newPriceSummaryListResponse(overall model.PriceSummary, groups []model.PriceSummary, groupBy string) view.PriceSummaryListResponse {
+	res := view.PriceSummaryListResponse{Summary: newPriceSummaryResponse(overall)}
+	if groupBy != model.GroupByNone {
+		groupList := make([]view.PriceSummaryGroupResponse, len(groups))
+		for i, group := range groups {
+			groupList[i] = view.PriceSummaryGroupResponse{Key: group.Key, PriceSummaryResponse: newPriceSummaryResponse(group)}
+		}
+		res.GroupBy = groupBy
+		res.Groups = &groupList
+	}
+	return res
+}
+
+// 価格の変更履歴をレスポンスの型に変換する
+func newPriceHistoryResponse(book model.Book, history []model.PriceHistory) view.PriceHistoryResponse {
+	historyList := make([]view.PriceChangeResponse, len(history))
+	for i, h := range history {
+		historyList[i] = view.PriceChangeResponse{
+			OldPrice:  h.OldPrice,
+			NewPrice:  h.NewPrice,
+			TrnID:     h.TrnID,
+			ChangedAt: view.FormatTime(h.ChangedAt),
+		}
+	}
+	return view.PriceHistoryResponse{
+		BookID:       book.ID,
+		CurrentPrice: book.Price,
+		LowestPrice:  book.LowestPrice,
+		History:      historyList,
+	}
+}
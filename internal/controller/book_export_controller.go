@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+)
+
+// エクスポートの形式
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+	exportFormatJSON   = "json"
+)
+
+// Excel が UTF-8 の CSV として認識するためのバイト順マーク
+const utf8BOM = "\ufeff"
+
+// CSV のヘッダー。name 〜 tags は取り込み (POST /books/import) の列名と同じ
+var exportCSVHeader = []string{"id", "name", "price", "isbn", "status", "author_ids", "tags",
+	"version", "created_at", "updated_at", "deleted_at"}
+
+// bookExporter は本を1件ずつ指定した形式で書き出す
+type bookExporter interface {
+	contentType() string
+	begin(w io.Writer) error
+	write(w io.Writer, book model.Book) error
+	end(w io.Writer) error
+}
+
+// ExportBooks は本の一覧を CSV・NDJSON・JSON で返すハンドラー
+// 一覧 (GET /books) と同じ絞り込み・並び順で、データベースから読み込んだ順に書き出す
+// format (デフォルト: csv) で形式を、bom=true で CSV の先頭に BOM を付けるかを指定する
+func (c *BookController) ExportBooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	query := r.URL.Query()
+
+	filter, userErr := parseBookFilter(r)
+	if userErr != nil {
+		entry.Errorf("クエリパラメータが不正です: %v", userErr)
+		view.RespondWithError(w, ctx, userErr)
+		return
+	}
+
+	bom := false
+	if v := query.Get("bom"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			entry.Errorf("クエリパラメータ'bom'の値が不正です: %s", v)
+			view.RespondWithError(w, ctx, errors.InvalidQueryParamError("bom"))
+			return
+		}
+		bom = b
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+	var exporter bookExporter
+	switch format {
+	case exportFormatCSV:
+		exporter = &csvExporter{bom: bom}
+	case exportFormatNDJSON:
+		exporter = &ndjsonExporter{}
+	case exportFormatJSON:
+		exporter = &jsonExporter{}
+	default:
+		entry.Errorf("クエリパラメータ'format'の値が不正です: %s", format)
+		view.RespondWithError(w, ctx, errors.InvalidQueryParamError("format"))
+		return
+	}
+
+	// 最初の1件を書き出すまではエラーレスポンスを返せるよう、ヘッダーの送信を遅らせる
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		w.Header().Set("Content-Type", exporter.contentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="books.`+format+`"`)
+		w.WriteHeader(http.StatusOK)
+		return exporter.begin(w)
+	}
+
+	entry.Infof("本のエクスポートを開始します: format=%s", format)
+	count, err := model.EachBook(ctx, c.DB, filter, func(book model.Book) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return exporter.write(w, book)
+	})
+	if err == nil {
+		if err = start(); err == nil {
+			err = exporter.end(w)
+		}
+	}
+	if err != nil {
+		if !started {
+			entry.Errorf("本のエクスポートに失敗しました: %v", err)
+			respondWithError(w, ctx, entry, err)
+			return
+		}
+		// ステータスコードは送信済みのため、出力を途中で打ち切る
+		entry.Errorf("本のエクスポートの途中で失敗したため、出力を中断しました (%d件出力済み): %v", count, err)
+		return
+	}
+	entry.Infof("本のエクスポートに成功しました (%d件)", count)
+}
+
+// エクスポートする本を JSON 用の型に変換する
+func newBookExportResponse(book model.Book) view.BookExportResponse {
+	return view.BookExportResponse{
+		BookResponse: newBookResponse(book, false, true),
+		AuthorIDs:    book.AuthorIDs,
+	}
+}
+
+// csvExporter は1行目をヘッダーとして CSV を書き出す
+// 著者ID・タグはセミコロン区切りで1つのセルにまとめる
+type csvExporter struct {
+	bom bool
+	cw  *csv.Writer
+}
+
+func (e *csvExporter) contentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e *csvExporter) begin(w io.Writer) error {
+	if e.bom {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
+	e.cw = csv.NewWriter(w)
+	return e.cw.Write(exportCSVHeader)
+}
+
+func (e *csvExporter) write(w io.Writer, book model.Book) error {
+	deletedAt := ""
+	if !book.DeletedAt.IsZero() {
+		deletedAt = view.FormatTime(book.DeletedAt)
+	}
+	return e.cw.Write([]string{
+		book.ID,
+		view.SafeCSVCell(book.Name),
+		strconv.Itoa(book.Price),
+		book.ISBN,
+		book.Status,
+		strings.Join(book.AuthorIDs, ";"),
+		view.SafeCSVCell(strings.Join(book.Tags, ";")),
+		strconv.Itoa(book.Version),
+		view.FormatTime(book.CreatedAt),
+		view.FormatTime(book.UpdatedAt),
+		deletedAt,
+	})
+}
+
+func (e *csvExporter) end(w io.Writer) error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// ndjsonExporter は1行に1件の JSON を書き出す
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExporter) contentType() string {
+	return "application/x-ndjson"
+}
+
+func (e *ndjsonExporter) begin(w io.Writer) error {
+	e.enc = json.NewEncoder(w)
+	return nil
+}
+
+func (e *ndjsonExporter) write(w io.Writer, book model.Book) error {
+	return e.enc.Encode(newBookExportResponse(book))
+}
+
+func (e *ndjsonExporter) end(w io.Writer) error {
+	return nil
+}
+
+// jsonExporter は本の JSON 配列を1要素ずつ書き出す
+type jsonExporter struct {
+	count int
+}
+
+func (e *jsonExporter) contentType() string {
+	return "application/json"
+}
+
+func (e *jsonExporter) begin(w io.Writer) error {
+	_, err := io.WriteString(w, "[")
+	return err
+}
+
+func (e *jsonExporter) write(w io.Writer, book model.Book) error {
+	data, err := json.Marshal(newBookExportResponse(book))
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) end(w io.Writer) error {
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	"github.com/HwaI12/go-api-tutorial/internal/importer"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+)
+
+// ImportBooks はリクエストボディの CSV・JSON 配列・NDJSON から本をまとめて登録するハンドラー
+// 形式はクエリパラメータ format、なければ Content-Type で判断する
+// 1件ずつ読み込んでコミットし、失敗した行は行番号とエラーコードをレスポンスで報告する
+func (c *BookController) ImportBooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = importFormatFromContentType(r.Header.Get("Content-Type"))
+	}
+	if !importer.IsValidFormat(format) {
+		entry.Errorf("取り込むファイルの形式が不正です: %q", format)
+		view.RespondWithError(w, ctx, errors.InvalidImportFormatError())
+		return
+	}
+
+	var opts importer.Options
+	if dryRun := query.Get("dry_run"); dryRun != "" {
+		b, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			entry.Errorf("クエリパラメータ'dry_run'の値が不正です: %s", dryRun)
+			view.RespondWithError(w, ctx, errors.InvalidQueryParamError("dry_run"))
+			return
+		}
+		opts.DryRun = b
+	}
+
+	mapping, err := importer.ParseMapping(query.Get("map"))
+	if err != nil {
+		entry.Errorf("列の対応の指定が不正です: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	reader, err := importer.NewReader(r.Body, format, mapping)
+	if err != nil {
+		entry.Errorf("取り込むファイルの読み込みに失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	entry.Infof("本の取り込みを開始します: format=%s, dry_run=%t", format, opts.DryRun)
+	report, err := importer.Import(ctx, c.DB, reader, opts)
+	if err != nil {
+		if userErr, ok := err.(*errors.UserDefinedError); ok && errors.IsRequestCanceled(userErr) {
+			entry.Warnf("クライアントが切断されたため、レスポンスを返却しません")
+			return
+		}
+		// 途中まで登録した行はコミット済みのため、それまでの件数をエラーメッセージに含める
+		entry.Errorf("取り込みを中断しました: %v", err)
+		view.RespondWithError(w, ctx, errors.ImportInterruptedError(report.Total, report.Imported, report.Failed))
+		return
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, report)
+}
+
+// Content-Type から取り込むファイルの形式を判断する。判断できない場合は空文字を返す
+func importFormatFromContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "text/csv":
+		return importer.FormatCSV
+	case "application/json":
+		return importer.FormatJSON
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return importer.FormatNDJSON
+	}
+	return ""
+}
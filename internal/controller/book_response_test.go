@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+)
+
+// go test ./internal/controller -update でゴールデンファイルを更新する
+var update = flag.Bool("update", false, "ゴールデンファイルを更新する")
+
+// テスト用の本。任意項目はすべて値を持つ
+func fixtureBook() model.Book {
+	createdAt := time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC)
+	return model.Book{
+		ID:              "11",
+		Name:            "Go言語入門",
+		Price:           2800,
+		ISBN:            "9784873119694",
+		Status:          model.StatusPurchased,
+		StatusChangedAt: createdAt.Add(48 * time.Hour),
+		StatusChangedBy: "librarian",
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt.Add(48 * time.Hour),
+		Version:         3,
+		AuthorIDs:       []string{"5"},
+		Authors: []model.Author{
+			{ID: "5", Name: "山田太郎", CreatedAt: createdAt.Add(-24 * time.Hour)},
+		},
+		Tags:        []string{"go", "programming"},
+		LowestPrice: 2500,
+	}
+}
+
+// テスト用の本。任意項目はすべて未設定
+func fixtureMinimalBook() model.Book {
+	createdAt := time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)
+	return model.Book{
+		ID:        "12",
+		Name:      "名前だけの本",
+		Price:     1000,
+		Status:    model.StatusWanted,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Version:   1,
+	}
+}
+
+// テスト用の価格の変更履歴。登録時の価格 (old_price が null) と変更1件
+func fixturePriceHistory() []model.PriceHistory {
+	changedAt := time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC)
+	registered, changed := 2800, 2500
+	return []model.PriceHistory{
+		{ID: "1", BookID: "11", NewPrice: registered, TrnID: "11111111-1111-1111-1111-111111111111", ChangedAt: changedAt},
+		{ID: "2", BookID: "11", OldPrice: &registered, NewPrice: changed, TrnID: "22222222-2222-2222-2222-222222222222", ChangedAt: changedAt.Add(24 * time.Hour)},
+	}
+}
+
+// テスト用の価格の集計結果。平均は小数第2位に丸めて返す
+func fixturePriceSummary(key string, count int) model.PriceSummary {
+	return model.PriceSummary{Key: key, Count: count, Total: 1000*count + 1, Average: (1000*float64(count) + 1) / float64(count), Min: 980, Max: 1021}
+}
+
+func TestBookResponseGolden(t *testing.T) {
+	ctx := context.WithValue(context.Background(), transaction.TrnIDKey, "00000000-0000-0000-0000-000000000000")
+	ctx = context.WithValue(ctx, transaction.TrnTimeKey, "2024-04-03T00:00:00Z")
+
+	books := []model.Book{fixtureBook(), fixtureMinimalBook()}
+	tests := []struct {
+		name   string
+		result interface{}
+	}{
+		{"book_detail", newBookDetailResponse(books[0])},
+		{"book_detail_minimal", newBookDetailResponse(books[1])},
+		{"book_list", view.BookListResponse{Books: []view.BookResponse{
+			newBookResponse(books[0], false, false),
+			newBookResponse(books[1], false, false),
+		}}},
+		{"book_list_embed", view.BookListResponse{Books: []view.BookResponse{
+			newBookResponse(books[0], true, true),
+			newBookResponse(books[1], true, true),
+		}}},
+		{"book_list_empty", view.BookListResponse{Books: []view.BookResponse{}}},
+		{"book_created", view.BookCreatedResponse{BookResponse: newBookDetailResponse(books[0])}},
+		{"book_export", newBookExportResponse(books[0])},
+		{"price_history", newPriceHistoryResponse(books[0], fixturePriceHistory())},
+		{"book_stats", view.BookStatsResponse{Total: 3, ByStatus: map[string]int{
+			model.StatusWanted: 1, model.StatusOrdered: 0, model.StatusPurchased: 2, model.StatusCancelled: 0,
+		}}},
+		{"price_summary", newPriceSummaryListResponse(fixturePriceSummary("", 3), nil, model.GroupByNone)},
+		{"price_summary_grouped", newPriceSummaryListResponse(fixturePriceSummary("", 3), []model.PriceSummary{
+			fixturePriceSummary(model.StatusPurchased, 2), fixturePriceSummary(model.StatusWanted, 1),
+		}, model.GroupByStatus)},
+		{"price_summary_grouped_empty", newPriceSummaryListResponse(model.PriceSummary{}, nil, model.GroupByTag)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.MarshalIndent(view.CreateResponse(ctx, tt.result), "", "  ")
+			if err != nil {
+				t.Fatalf("JSON への変換に失敗しました: %v", err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", tt.name+".golden.json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("ゴールデンファイルの書き込みに失敗しました: %v", err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ゴールデンファイルの読み込みに失敗しました: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("レスポンスがゴールデンファイル %s と一致しません\n--- got\n%s\n--- want\n%s", path, got, want)
+			}
+		})
+	}
+}
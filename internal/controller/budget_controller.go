@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/gorilla/mux"
+)
+
+// 月ごとの予算に関する操作を行うコントローラー
+type BudgetController struct {
+	DB *sql.DB
+}
+
+// 新しい BudgetController を作成して返す
+func NewBudgetController(db *sql.DB) *BudgetController {
+	return &BudgetController{DB: db}
+}
+
+// GetBudget は指定した月の予算と残額を返すハンドラー
+// 欲しい本の合計金額が残額を超えている場合は over_budget が true になる
+func (c *BudgetController) GetBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	month := mux.Vars(r)["month"]
+
+	entry.Infof("予算の取得を開始します: month=%s", month)
+	budget, err := model.GetBudget(ctx, c.DB, month)
+	if err != nil {
+		entry.Errorf("予算の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("予算の取得に成功しました")
+
+	view.RespondWithJSON(w, ctx, http.StatusOK, budgetToMap(*budget))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// PutBudget は指定した月の予算を設定するハンドラー
+func (c *BudgetController) PutBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	month := mux.Vars(r)["month"]
+
+	if _, err := model.ParseBudgetMonth(month); err != nil {
+		entry.Errorf("月の形式が不正です: %s", month)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	var input model.BudgetInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	if input.Amount == nil {
+		entry.Errorf("パラメータ'amount'がありません")
+		view.RespondWithError(w, ctx, errors.ParamMissingError("amount"))
+		return
+	}
+
+	budget := model.Budget{Month: month, Amount: *input.Amount}
+	entry.Infof("予算の保存を開始します")
+	if err := budget.SaveBudget(ctx, c.DB); err != nil {
+		entry.Errorf("予算の保存に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("予算の保存に成功しました")
+
+	saved, err := model.GetBudget(ctx, c.DB, month)
+	if err != nil {
+		entry.Errorf("保存後の予算の取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, budgetToMap(*saved))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// 予算をレスポンス用のデータに変換する
+func budgetToMap(budget model.Budget) map[string]interface{} {
+	return map[string]interface{}{
+		"month":        budget.Month,
+		"amount":       budget.Amount,
+		"is_default":   budget.IsDefault,
+		"spent":        budget.Spent,
+		"ordered":      budget.Ordered,
+		"remaining":    budget.Remaining(),
+		"wanted_total": budget.Wanted,
+		"over_budget":  budget.OverBudget(),
+	}
+}
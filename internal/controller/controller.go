@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/sirupsen/logrus"
+)
+
+// respondWithError はモデルから返されたエラーをレスポンスとして返す
+// クライアントがリクエストを中断している場合はレスポンスを返さずログのみ記録する
+func respondWithError(w http.ResponseWriter, ctx context.Context, entry *logrus.Entry, err error) {
+	userErr, ok := err.(*errors.UserDefinedError)
+	if !ok {
+		entry.Errorf("予期しないエラーが発生しました: %v", err)
+		userErr = errors.UnexpectedError()
+	}
+	if errors.IsRequestCanceled(userErr) {
+		entry.Warnf("クライアントが切断されたため、レスポンスを返却しません")
+		return
+	}
+	view.RespondWithError(w, ctx, userErr)
+}
+
+// 数値のIDとして妥当な文字列かどうかを判定する
+func isID(s string) bool {
+	if s == "" || len(s) > 19 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// 空文字をレスポンスの null として扱う
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// 空文字をレスポンスの型の null (nil) として扱う
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ゼロ値の日時をレスポンスの型の null (nil) として扱う
+func optionalTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	formatted := view.FormatTime(t)
+	return &formatted
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/gorilla/mux"
+)
+
+// タグに関する操作を行うコントローラー
+type TagController struct {
+	DB *sql.DB
+}
+
+// 新しい TagController を作成して返す
+func NewTagController(db *sql.DB) *TagController {
+	return &TagController{DB: db}
+}
+
+// GetTags はタグの一覧を本の数とともに返すハンドラー
+func (c *TagController) GetTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	entry.Infof("タグの一覧取得を開始します")
+	tags, err := model.GetTags(ctx, c.DB)
+	if err != nil {
+		entry.Errorf("タグの一覧取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("タグの一覧取得に成功しました")
+
+	tagList := make([]map[string]interface{}, len(tags))
+	for i, tag := range tags {
+		tagList[i] = tagToMap(tag)
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, map[string]interface{}{
+		"tags": tagList,
+	})
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// RenameTag は指定したIDのタグの名前を変更するハンドラー
+func (c *TagController) RenameTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	id := mux.Vars(r)["id"]
+
+	var input model.TagInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	if input.Name == nil {
+		entry.Errorf("パラメータ'name'がありません")
+		view.RespondWithError(w, ctx, errors.ParamNameMissingError())
+		return
+	}
+
+	name, err := model.NormalizeTagName(ctx, *input.Name)
+	if err != nil {
+		entry.Errorf("バリデーションに失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	tag, err := model.GetTag(ctx, c.DB, id)
+	if err != nil {
+		entry.Errorf("タグの取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+
+	tag.Name = name
+	entry.Infof("タグの名前の変更を開始します")
+	if err := tag.RenameTag(ctx, c.DB); err != nil {
+		entry.Errorf("タグの名前の変更に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("タグの名前の変更に成功しました")
+
+	view.RespondWithJSON(w, ctx, http.StatusOK, tagToMap(*tag))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// MergeTags は指定したIDのタグを別のタグに統合するハンドラー
+func (c *TagController) MergeTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+	sourceID := mux.Vars(r)["id"]
+
+	var input model.TagMergeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		entry.Errorf("リクエストボディのデコードに失敗しました: %v", err)
+		view.RespondWithError(w, ctx, errors.InvalidRequestError())
+		return
+	}
+	if input.Into == nil {
+		entry.Errorf("パラメータ'into'がありません")
+		view.RespondWithError(w, ctx, errors.ParamMissingError("into"))
+		return
+	}
+	targetID := *input.Into
+
+	// 統合元と統合先のタグが存在することを確認する
+	source, err := model.GetTag(ctx, c.DB, sourceID)
+	if err != nil {
+		entry.Errorf("統合元のタグの取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	if !isID(targetID) {
+		entry.Errorf("統合先のタグが見つかりません: %s", targetID)
+		view.RespondWithError(w, ctx, errors.TagNotFoundError())
+		return
+	}
+	target, err := model.GetTag(ctx, c.DB, targetID)
+	if err != nil {
+		entry.Errorf("統合先のタグの取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	// "1" と "01" のように表記が異なる同じIDを区別しないよう、データベースから取得したIDで比較する
+	sourceID, targetID = source.ID, target.ID
+	if targetID == sourceID {
+		entry.Errorf("統合元と統合先に同じタグが指定されています: %s", sourceID)
+		view.RespondWithError(w, ctx, errors.TagMergeSameError())
+		return
+	}
+
+	entry.Infof("タグの統合を開始します: %s -> %s", sourceID, targetID)
+	if err := model.MergeTags(ctx, c.DB, sourceID, targetID); err != nil {
+		entry.Errorf("タグの統合に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	entry.Infof("タグの統合に成功しました")
+
+	merged, err := model.GetTag(ctx, c.DB, targetID)
+	if err != nil {
+		entry.Errorf("統合後のタグの取得に失敗しました: %v", err)
+		respondWithError(w, ctx, entry, err)
+		return
+	}
+	view.RespondWithJSON(w, ctx, http.StatusOK, tagToMap(*merged))
+	entry.Infof("レスポンスの返却に成功しました")
+}
+
+// タグをレスポンス用のデータに変換する
+func tagToMap(tag model.Tag) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         tag.ID,
+		"name":       tag.Name,
+		"book_count": tag.BookCount,
+		"created_at": view.FormatTime(tag.CreatedAt),
+	}
+}
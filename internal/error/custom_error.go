@@ -5,6 +5,9 @@ import (
 	"net/http"
 )
 
+// クライアントがリクエストを中断したことを表すステータスコード (nginx の慣例に合わせる)
+const StatusClientClosedRequest = 499
+
 // UserDefinedError カスタムエラー型
 type UserDefinedError struct {
 	ErrorCode      string `json:"error_code"`
@@ -27,6 +30,31 @@ func EnvLoadError() *UserDefinedError {
 	return &UserDefinedError{"ENV-ERR-500-00", ".envファイルの読み込みに失敗しました", http.StatusInternalServerError}
 }
 
+func InvalidStatusTransitionError(from, to string) *UserDefinedError {
+	return &UserDefinedError{"BUSN-ERR-409-00", fmt.Sprintf("本のステータスを'%s'から'%s'に変更することはできません", from, to), http.StatusConflict}
+}
+
+func PreconditionFailedError() *UserDefinedError {
+	return &UserDefinedError{"BUSN-ERR-412-00", "本が他のリクエストで更新されています。最新の内容を取得してから再度実行してください", http.StatusPreconditionFailed}
+}
+
+func VersionConflictError(current int) *UserDefinedError {
+	return &UserDefinedError{"BUSN-ERR-409-02", fmt.Sprintf("本が他のリクエストで更新されています。最新の内容を取得してから再度実行してください (現在のバージョン: %d)", current), http.StatusConflict}
+}
+
+// IsVersionConflict はバージョンの不一致による更新の失敗を表すエラーかどうかを判定する
+func IsVersionConflict(err *UserDefinedError) bool {
+	return err != nil && err.ErrorCode == VersionConflictError(0).ErrorCode
+}
+
+func IdempotencyKeyInProgressError() *UserDefinedError {
+	return &UserDefinedError{"BUSN-ERR-409-01", "同じIdempotency-Keyのリクエストを処理中です。しばらくしてから再試行してください", http.StatusConflict}
+}
+
+func BatchOperationRolledBackError() *UserDefinedError {
+	return &UserDefinedError{"BUSN-ERR-424-00", "バッチ内の他の操作が失敗したため、この操作は取り消されました", http.StatusFailedDependency}
+}
+
 func DatabaseConnectionError() *UserDefinedError {
 	return &UserDefinedError{"DB-ERR-500-00", "データベースへの接続に失敗しました", http.StatusInternalServerError}
 }
@@ -59,10 +87,68 @@ func DatabaseSelectError() *UserDefinedError {
 	return &UserDefinedError{"DB-ERR-500-07", "データベースからの取得に失敗しました", http.StatusInternalServerError}
 }
 
+func DatabaseTimeoutError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-504-00", "データベース処理がタイムアウトしました", http.StatusGatewayTimeout}
+}
+
+// クライアントがリクエストを中断した場合のエラー。ログにのみ記録し、レスポンスは返さない
+func RequestCanceledError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-499-00", "クライアントによりリクエストが中断されました", StatusClientClosedRequest}
+}
+
+// IsRequestCanceled はクライアントによる中断を表すエラーかどうかを判定する
+func IsRequestCanceled(err *UserDefinedError) bool {
+	return err != nil && err.ErrorCode == RequestCanceledError().ErrorCode
+}
+
 func NoDataFoundError() *UserDefinedError {
 	return &UserDefinedError{"DB-ERR-404-00", "取得するデータがありません", http.StatusNotFound}
 }
 
+func DatabaseUpdateError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-500-08", "データベースの更新に失敗しました", http.StatusInternalServerError}
+}
+
+func DatabaseTransactionError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-500-10", "データベースのトランザクションの処理に失敗しました", http.StatusInternalServerError}
+}
+
+func DatabaseDeleteError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-500-09", "データベースからの削除に失敗しました", http.StatusInternalServerError}
+}
+
+func BookNotFoundError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-404-01", "指定された本が見つかりません", http.StatusNotFound}
+}
+
+func AuthorNotFoundError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-404-02", "指定された著者が見つかりません", http.StatusNotFound}
+}
+
+func TagNotFoundError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-404-03", "指定されたタグが見つかりません", http.StatusNotFound}
+}
+
+func TagNameConflictError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-409-00", "同じ名前のタグが既に存在します。タグをまとめる場合は統合してください", http.StatusConflict}
+}
+
+func TagNameDuplicateError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-38", "パラメータ'tags'に同じタグとみなされる名前が含まれています", http.StatusBadRequest}
+}
+
+func DuplicateISBNError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-409-01", "同じISBNの本が既に登録されています", http.StatusConflict}
+}
+
+func DeletedBookNotFoundError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-404-05", "指定された削除済みの本が見つかりません", http.StatusNotFound}
+}
+
+func BudgetNotFoundError() *UserDefinedError {
+	return &UserDefinedError{"DB-ERR-404-04", "指定された月の予算が設定されていません", http.StatusNotFound}
+}
+
 func ServerStartError() *UserDefinedError {
 	return &UserDefinedError{"SRV-ERR-500-00", "サーバーの起動に失敗しました", http.StatusInternalServerError}
 }
@@ -79,6 +165,10 @@ func InvalidAPIKeyError() *UserDefinedError {
 	return &UserDefinedError{"AUTH-ERR-401-01", "APIキーが無効です", http.StatusUnauthorized}
 }
 
+func AdminRequiredError() *UserDefinedError {
+	return &UserDefinedError{"AUTH-ERR-403-00", "この操作は管理者のみ実行できます", http.StatusForbidden}
+}
+
 func InvalidRequestError() *UserDefinedError {
 	return &UserDefinedError{"VAL-ERR-400-07", "リクエストボディのデコードに失敗しました", http.StatusBadRequest}
 }
@@ -99,14 +189,146 @@ func BookPriceEmptyError() *UserDefinedError {
 	return &UserDefinedError{"VAL-ERR-400-03", "パラメータ'price'が0です。本の価格を入力してください", http.StatusBadRequest}
 }
 
-func BookNameTooLongError() *UserDefinedError {
-	return &UserDefinedError{"VAL-ERR-400-04", "パラメータ'name'が長すぎます。50文字以内で書いてください", http.StatusBadRequest}
+func BookNameTooLongError(maxLength int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-04", fmt.Sprintf("パラメータ'name'が長すぎます。%d文字以内で書いてください", maxLength), http.StatusBadRequest}
 }
 
 func BookPriceNegativeError() *UserDefinedError {
 	return &UserDefinedError{"VAL-ERR-400-05", "パラメータ'price'が0以下です。正の整数を入力してください", http.StatusBadRequest}
 }
 
-func BookPriceTooHighError() *UserDefinedError {
-	return &UserDefinedError{"VAL-ERR-400-06", "パラメータ'price'が高すぎます。20000円以内で書いてください", http.StatusBadRequest}
+func BookPriceTooHighError(maxPrice int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-06", fmt.Sprintf("パラメータ'price'が高すぎます。%d円以内で書いてください", maxPrice), http.StatusBadRequest}
+}
+
+func BookNameTooShortError(minLength int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-08", fmt.Sprintf("パラメータ'name'が短すぎます。%d文字以上で書いてください", minLength), http.StatusBadRequest}
+}
+
+func BookPriceTooLowError(minPrice int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-09", fmt.Sprintf("パラメータ'price'が低すぎます。%d円以上で書いてください", minPrice), http.StatusBadRequest}
+}
+
+func AuthorNameEmptyError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-10", "パラメータ'name'が空です。著者の名前を入力してください", http.StatusBadRequest}
+}
+
+func AuthorNameTooLongError(maxLength int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-11", fmt.Sprintf("パラメータ'name'が長すぎます。著者の名前は%d文字以内で書いてください", maxLength), http.StatusBadRequest}
+}
+
+func InvalidQueryParamError(name string) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-12", fmt.Sprintf("クエリパラメータ'%s'の値が不正です", name), http.StatusBadRequest}
+}
+
+func UnknownAuthorIDError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-13", "パラメータ'author_ids'に存在しない著者IDが含まれています", http.StatusBadRequest}
+}
+
+func AuthorNameTooShortError(minLength int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-14", fmt.Sprintf("パラメータ'name'が短すぎます。著者の名前は%d文字以上で書いてください", minLength), http.StatusBadRequest}
+}
+
+func TagNameEmptyError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-15", "タグの名前が空です。タグの名前を入力してください", http.StatusBadRequest}
+}
+
+func TagNameTooLongError(maxLength int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-16", fmt.Sprintf("タグの名前が長すぎます。%d文字以内で書いてください", maxLength), http.StatusBadRequest}
+}
+
+func TagNameInvalidError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-17", "タグの名前に使用できない文字が含まれています", http.StatusBadRequest}
+}
+
+func TooManyTagsError(maxTags int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-18", fmt.Sprintf("パラメータ'tags'が多すぎます。タグは%d個以内で指定してください", maxTags), http.StatusBadRequest}
+}
+
+func TagNameTooShortError(minLength int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-19", fmt.Sprintf("タグの名前が短すぎます。%d文字以上で書いてください", minLength), http.StatusBadRequest}
+}
+
+func ParamMissingError(name string) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-20", fmt.Sprintf("パラメータ'%s'がありません。パラメータを正しく設定するか、値を入力してください", name), http.StatusBadRequest}
+}
+
+func TagMergeSameError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-21", "統合元と統合先に同じタグが指定されています", http.StatusBadRequest}
+}
+
+func ISBNFormatError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-22", "パラメータ'isbn'の形式が不正です。ISBN-10またはISBN-13を入力してください", http.StatusBadRequest}
+}
+
+func ISBNChecksumError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-23", "パラメータ'isbn'のチェックディジットが一致しません", http.StatusBadRequest}
+}
+
+func InvalidStatusError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-24", "パラメータ'status'の値が不正です。wanted, ordered, purchased, cancelledのいずれかを指定してください", http.StatusBadRequest}
+}
+
+func BudgetAmountNegativeError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-25", "パラメータ'amount'が負の値です。0以上の整数を入力してください", http.StatusBadRequest}
+}
+
+func InvalidMonthError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-26", "月の形式が不正です。YYYY-MM形式で指定してください", http.StatusBadRequest}
+}
+
+func InvalidIdempotencyKeyError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-27", "Idempotency-Keyが不正です。1文字以上255文字以内で指定してください", http.StatusBadRequest}
+}
+
+func VersionMismatchError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-28", "パラメータ'version'とIf-Matchのバージョンが一致しません", http.StatusBadRequest}
+}
+
+func BatchTooLargeError(max int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-29", fmt.Sprintf("パラメータ'operations'が多すぎます。%d件以内で指定してください", max), http.StatusBadRequest}
+}
+
+func InvalidBatchModeError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-30", "パラメータ'mode'の値が不正です。atomic, best_effortのいずれかを指定してください", http.StatusBadRequest}
+}
+
+func InvalidBatchOperationError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-31", "パラメータ'op'の値が不正です。create, update, deleteのいずれかを指定してください", http.StatusBadRequest}
+}
+
+func InvalidImportFormatError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-32", "パラメータ'format'の値が不正です。csv, json, ndjsonのいずれかを指定してください", http.StatusBadRequest}
+}
+
+func InvalidColumnMappingError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-33", "パラメータ'map'の形式が不正です。'列名:項目名'をカンマ区切りで指定してください (項目名: name, price, isbn, author_ids, tags)", http.StatusBadRequest}
+}
+
+func ImportColumnMissingError(column string) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-34", fmt.Sprintf("CSVのヘッダーに列'%s'がありません", column), http.StatusBadRequest}
+}
+
+func ImportRowFormatError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-35", "行の内容を読み込めません。値の形式を確認してください", http.StatusBadRequest}
+}
+
+func ImportFileFormatError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-36", "ファイルの形式が不正なため、これ以降の行を読み込めません", http.StatusBadRequest}
+}
+
+func ImportInterruptedError(total, imported, failed int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-400-37", fmt.Sprintf("ファイルの形式が不正なため、%d件目より後の行を読み込めません (それまでの結果: 成功 %d件、失敗 %d件)", total, imported, failed), http.StatusBadRequest}
+}
+
+func NotAcceptableError(mediaTypes string) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-406-00", fmt.Sprintf("Acceptヘッダーで指定された形式には対応していません。%sのいずれかを指定してください", mediaTypes), http.StatusNotAcceptable}
+}
+
+func RequestBodyTooLargeError(maxBytes int) *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-413-00", fmt.Sprintf("リクエストボディが大きすぎます。%dバイト以内にしてください", maxBytes), http.StatusRequestEntityTooLarge}
+}
+
+func IdempotencyKeyMismatchError() *UserDefinedError {
+	return &UserDefinedError{"VAL-ERR-422-00", "同じIdempotency-Keyが異なるリクエストで使用されています", http.StatusUnprocessableEntity}
 }
@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"io"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// 取り込みの各行の結果
+const (
+	RowImported    = "imported"     // 登録した
+	RowWouldImport = "would_import" // ドライランのため登録していない
+	RowFailed      = "failed"       // 登録に失敗した
+)
+
+// ドライランで登録した本を取り消すためのエラー
+var errDryRun = stderrors.New("ドライランのためロールバックします")
+
+// Options は取り込みの設定
+type Options struct {
+	DryRun bool // true の場合は登録せず、登録される内容と失敗する行のみ報告する
+}
+
+// RowResult は取り込んだ1件の結果
+type RowResult struct {
+	Line         int    `json:"line"`
+	Result       string `json:"result"`
+	ID           string `json:"id,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Price        *int   `json:"price,omitempty"`
+	ISBN         string `json:"isbn,omitempty"`
+	ErrorCode    string `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// Report に含める行の結果の最大数
+const maxReportRows = 1000
+
+// Report は取り込みの結果
+// Rows には失敗した行と、ドライランの場合は登録される予定の行を含める
+// 大きなファイルでも結果が膨らまないよう、登録に成功した行は件数のみ数え、
+// Rows は先頭の maxReportRows 件までとする (超えた場合は RowsTruncated を true にする)
+type Report struct {
+	DryRun        bool        `json:"dry_run"`
+	Total         int         `json:"total"`
+	Imported      int         `json:"imported"`
+	Failed        int         `json:"failed"`
+	Rows          []RowResult `json:"rows"`
+	RowsTruncated bool        `json:"rows_truncated"`
+}
+
+// 行の結果を Rows に追加する。maxReportRows 件を超えた場合は件数のみ数える
+func (r *Report) addRow(row RowResult) {
+	if len(r.Rows) >= maxReportRows {
+		r.RowsTruncated = true
+		return
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// Import はファイルから1件ずつ本を読み込み、CreateBook と同じ検証を行って登録する
+// 1件ごとにコミットするため、失敗した行があってもそれ以外の行は登録される
+// ドライランの場合は1件ごとのトランザクションで登録してからロールバックし、
+// ISBN の重複や存在しない著者などデータベースでの検証も行う (自動採番のIDは消費される)
+// ファイルの形式が不正で読み込みを続けられない場合は、それまでの結果とエラーを返す
+func Import(ctx context.Context, db *sql.DB, r Reader, opts Options) (*Report, error) {
+	entry := logger.WithTransaction(ctx)
+	report := &Report{DryRun: opts.DryRun, Rows: []RowResult{}}
+	// ドライランでは登録しないため、ファイル内の ISBN の重複はデータベースで検出できない
+	// 登録される予定の行の ISBN を記録し、同じ ISBN の行を失敗とする
+	seenISBNs := map[string]bool{}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, errors.RequestCanceledError()
+		}
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			entry.Errorf("ファイルの読み込みに失敗しました (%d件目の後): %v", report.Total, err)
+			return report, err
+		}
+		report.Total++
+
+		result := importRecord(ctx, db, record, opts)
+		if result.Result == RowWouldImport && result.ISBN != "" {
+			if seenISBNs[result.ISBN] {
+				userErr := errors.DuplicateISBNError()
+				result.Result = RowFailed
+				result.ErrorCode = userErr.ErrorCode
+				result.ErrorMessage = userErr.ErrorMessage
+			} else {
+				seenISBNs[result.ISBN] = true
+			}
+		}
+		switch result.Result {
+		case RowFailed:
+			entry.Warnf("%d行目の取り込みに失敗しました: [%s] %s", result.Line, result.ErrorCode, result.ErrorMessage)
+			report.Failed++
+			report.addRow(result)
+		case RowWouldImport:
+			report.Imported++
+			report.addRow(result)
+		default:
+			report.Imported++
+			metrics.BooksCreatedTotal.Inc()
+		}
+	}
+	entry.Infof("取り込みが終了しました: dry_run=%t, 全体=%d件, 成功=%d件, 失敗=%d件",
+		opts.DryRun, report.Total, report.Imported, report.Failed)
+	return report, nil
+}
+
+// 1件を検証して登録する
+func importRecord(ctx context.Context, db *sql.DB, record *Record, opts Options) RowResult {
+	result := RowResult{Line: record.Line, Result: RowFailed}
+	fail := func(err error) RowResult {
+		userErr, ok := err.(*errors.UserDefinedError)
+		if !ok {
+			userErr = errors.UnexpectedError()
+		}
+		result.ErrorCode = userErr.ErrorCode
+		result.ErrorMessage = userErr.ErrorMessage
+		return result
+	}
+
+	if record.Input.Name != nil {
+		result.Name = *record.Input.Name
+	}
+	result.Price = record.Input.Price
+	if record.Err != nil {
+		return fail(record.Err)
+	}
+
+	book, err := model.NewBookFromInput(ctx, record.Input)
+	if err != nil {
+		return fail(err)
+	}
+	if err := book.Validate(ctx); err != nil {
+		return fail(err)
+	}
+	result.ISBN = book.ISBN
+
+	if opts.DryRun {
+		err := database.RunInTx(ctx, db, func(ctx context.Context) error {
+			if err := book.CreateBook(ctx, db); err != nil {
+				return err
+			}
+			return errDryRun
+		})
+		if err != errDryRun {
+			return fail(err)
+		}
+		result.Result = RowWouldImport
+		return result
+	}
+
+	if err := book.CreateBook(ctx, db); err != nil {
+		return fail(err)
+	}
+	result.ID = book.ID
+	result.Result = RowImported
+	return result
+}
@@ -0,0 +1,317 @@
+// Package importer は CSV・JSON・NDJSON のファイルから本をまとめて登録する
+// ファイルは1件ずつ読み込むため、大きなファイルでも全体をメモリに読み込まない
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"strconv"
+	"strings"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	model "github.com/HwaI12/go-api-tutorial/internal/model"
+)
+
+// 取り込むファイルの形式
+const (
+	FormatCSV    = "csv"    // 1行目がヘッダーの CSV
+	FormatJSON   = "json"   // 本の JSON 配列
+	FormatNDJSON = "ndjson" // 1行に1件の JSON
+)
+
+// 取り込みの対象となる本の項目
+var fields = []string{"name", "price", "isbn", "author_ids", "tags"}
+
+// CSV のセルで著者ID・タグを区切る文字
+const listSeparator = ";"
+
+// Record は取り込むファイルの1件分のデータ
+// 行の内容を読み込めなかった場合は Err にエラーを設定する
+type Record struct {
+	Line  int // 1件が始まる行番号 (1始まり)
+	Input model.BookInput
+	Err   *errors.UserDefinedError
+}
+
+// Reader はファイルから1件ずつ本を読み込む
+// すべて読み込んだ場合は io.EOF を返す
+type Reader interface {
+	Next() (*Record, error)
+}
+
+// IsValidFormat は取り込むファイルの形式として正しい値かどうかを判定する
+func IsValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON || format == FormatNDJSON
+}
+
+// ParseMapping は "列名:項目名" をカンマ区切りで並べた CSV の列の対応を解析する
+// 例: "書名:name,価格:price"
+func ParseMapping(spec string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		column, field, ok := strings.Cut(pair, ":")
+		column = strings.TrimSpace(column)
+		field = strings.TrimSpace(field)
+		if !ok || column == "" || !isField(field) {
+			return nil, errors.InvalidColumnMappingError()
+		}
+		mapping[column] = field
+	}
+	return mapping, nil
+}
+
+func isField(name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NewReader は指定した形式のファイルを読み込む Reader を作成する
+// mapping は CSV の列名と項目名の対応で、指定されていない列は列名と項目名が一致するものを使用する
+func NewReader(r io.Reader, format string, mapping map[string]string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, mapping)
+	case FormatJSON:
+		return newJSONReader(r), nil
+	case FormatNDJSON:
+		return &ndjsonReader{r: bufio.NewReader(r)}, nil
+	default:
+		return nil, errors.InvalidImportFormatError()
+	}
+}
+
+// csvReader は1行目をヘッダーとして CSV を読み込む
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int // 項目名ごとの列の位置
+}
+
+func newCSVReader(r io.Reader, mapping map[string]string) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.ImportColumnMissingError("name")
+	}
+	if err != nil {
+		return nil, errors.ImportFileFormatError()
+	}
+
+	columns := map[string]int{}
+	found := map[string]bool{}
+	for i, name := range header {
+		// Excel で保存した CSV の BOM を取り除く
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.TrimSpace(name)
+		found[name] = true
+		if field, ok := mapping[name]; ok {
+			columns[field] = i
+		} else if f := strings.ToLower(name); isField(f) {
+			if _, ok := columns[f]; !ok {
+				columns[f] = i
+			}
+		}
+	}
+	for column := range mapping {
+		if !found[column] {
+			return nil, errors.ImportColumnMissingError(column)
+		}
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errors.ImportColumnMissingError(required)
+		}
+	}
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (*Record, error) {
+	row, err := c.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if stderrors.As(err, &parseErr) {
+		// 引用符の誤りなどはその行のみ失敗とし、次の行から読み込みを続ける
+		return &Record{Line: parseErr.StartLine, Err: errors.ImportRowFormatError()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	record := &Record{Line: line}
+	cell := func(field string) (string, bool) {
+		i, ok := c.columns[field]
+		if !ok || i >= len(row) {
+			return "", false
+		}
+		v := unescapeCSVCell(strings.TrimSpace(row[i]))
+		return v, v != ""
+	}
+
+	if v, ok := cell("name"); ok {
+		record.Input.Name = &v
+	}
+	if v, ok := cell("price"); ok {
+		// 表計算ソフトの桁区切りを取り除く
+		price, err := strconv.Atoi(strings.ReplaceAll(v, ",", ""))
+		if err != nil {
+			record.Err = errors.ImportRowFormatError()
+			return record, nil
+		}
+		record.Input.Price = &price
+	}
+	if v, ok := cell("isbn"); ok {
+		record.Input.ISBN = &v
+	}
+	if v, ok := cell("author_ids"); ok {
+		ids := splitList(v)
+		record.Input.AuthorIDs = &ids
+	}
+	if v, ok := cell("tags"); ok {
+		tags := splitList(v)
+		record.Input.Tags = &tags
+	}
+	return record, nil
+}
+
+// エクスポートした CSV で数式にならないよう先頭に付けた ' を取り除く (views.SafeCSVCell の逆)
+func unescapeCSVCell(v string) string {
+	if len(v) >= 2 && v[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
+
+// セミコロン区切りのセルを一覧に分割する
+func splitList(v string) []string {
+	list := []string{}
+	for _, s := range strings.Split(v, listSeparator) {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// jsonReader は本の JSON 配列を1要素ずつ読み込む
+type jsonReader struct {
+	lines   *lineTracker
+	dec     *json.Decoder
+	started bool
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	// BOM はデコーダーに渡す前に取り除く (表計算ソフトが出力するファイルに付くことがある)
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	lines := &lineTracker{r: br}
+	return &jsonReader{lines: lines, dec: json.NewDecoder(lines)}
+}
+
+func (j *jsonReader) Next() (*Record, error) {
+	if !j.started {
+		j.started = true
+		if tok, err := j.dec.Token(); err != nil || tok != json.Delim('[') {
+			return nil, errors.ImportFileFormatError()
+		}
+	}
+	if !j.dec.More() {
+		if _, err := j.dec.Token(); err != nil {
+			return nil, errors.ImportFileFormatError()
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := j.dec.Decode(&raw); err != nil {
+		return nil, errors.ImportFileFormatError()
+	}
+	// 要素の終わりの位置から、要素が始まる行を求める
+	record := &Record{Line: j.lines.lineAt(j.dec.InputOffset() - int64(len(raw)))}
+	if err := json.Unmarshal(raw, &record.Input); err != nil {
+		record.Input = model.BookInput{}
+		record.Err = errors.ImportRowFormatError()
+	}
+	return record, nil
+}
+
+// lineTracker は読み込んだ改行の位置を記録し、位置から行番号を求める
+// 求めた位置より前の改行は破棄するため、保持する改行はデコーダーの先読みの分のみ
+type lineTracker struct {
+	r        io.Reader
+	read     int64
+	newlines []int64
+	passed   int
+}
+
+func (t *lineTracker) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			t.newlines = append(t.newlines, t.read+int64(i))
+		}
+	}
+	t.read += int64(n)
+	return n, err
+}
+
+// lineAt は offset の位置の行番号を返す。offset は前回の呼び出しより後ろの位置であること
+func (t *lineTracker) lineAt(offset int64) int {
+	for len(t.newlines) > 0 && t.newlines[0] < offset {
+		t.newlines = t.newlines[1:]
+		t.passed++
+	}
+	return t.passed + 1
+}
+
+// ndjsonReader は1行に1件の JSON を読み込む。空行は読み飛ばす
+type ndjsonReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (n *ndjsonReader) Next() (*Record, error) {
+	for {
+		data, err := n.r.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		n.line++
+		data = bytes.TrimSpace(data)
+		if n.line == 1 {
+			data = bytes.TrimPrefix(data, []byte("\ufeff"))
+		}
+		if len(data) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		record := &Record{Line: n.line}
+		if err := json.Unmarshal(data, &record.Input); err != nil {
+			record.Input = model.BookInput{}
+			record.Err = errors.ImportRowFormatError()
+		}
+		return record, nil
+	}
+}
@@ -0,0 +1,267 @@
+package importer
+
+import (
+	stderrors "errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+)
+
+const (
+	rowFormat  = "VAL-ERR-400-35"
+	fileFormat = "VAL-ERR-400-36"
+)
+
+// 読み込んだ1件の要約 (テストで比較する項目のみ)
+type readResult struct {
+	Line int
+	Name string
+	Err  string
+}
+
+// すべての件を読み込み、要約と最後に返したエラーのコードを返す
+// 途中でファイルの形式が不正になった場合は、そこまでの件とエラーのコードを返す
+func readAll(t *testing.T, r Reader) ([]readResult, string) {
+	t.Helper()
+	var results []readResult
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return results, ""
+		}
+		if err != nil {
+			var userErr *errors.UserDefinedError
+			if !stderrors.As(err, &userErr) {
+				t.Fatalf("予期しないエラー: %v", err)
+			}
+			return results, userErr.ErrorCode
+		}
+		result := readResult{Line: record.Line}
+		if record.Input.Name != nil {
+			result.Name = *record.Input.Name
+		}
+		if record.Err != nil {
+			result.Err = record.Err.ErrorCode
+		}
+		results = append(results, result)
+	}
+}
+
+func newTestReader(t *testing.T, input, format string, mapping map[string]string) Reader {
+	t.Helper()
+	r, err := NewReader(strings.NewReader(input), format, mapping)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return r
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mapping map[string]string
+		want    []readResult
+	}{
+		{
+			name:  "行番号",
+			input: "name,price\nGo入門,2800\n\nWeb入門,1980\n",
+			want:  []readResult{{Line: 2, Name: "Go入門"}, {Line: 4, Name: "Web入門"}},
+		},
+		{
+			name:  "引用符で囲んだ複数行のセルは開始行を返す",
+			input: "name,price\n\"1行目\n2行目\",100\n次の本,200\n",
+			want:  []readResult{{Line: 2, Name: "1行目\n2行目"}, {Line: 4, Name: "次の本"}},
+		},
+		{
+			name:  "BOM と大文字のヘッダー",
+			input: "\ufeffName,PRICE\nGo入門,2800\n",
+			want:  []readResult{{Line: 2, Name: "Go入門"}},
+		},
+		{
+			name:    "列の対応",
+			input:   "書名,価格,name\nGo入門,\"2,800\",無視する列\n",
+			mapping: map[string]string{"書名": "name", "価格": "price"},
+			want:    []readResult{{Line: 2, Name: "Go入門"}},
+		},
+		{
+			name:  "数式にならないよう付けた ' を取り除く",
+			input: "name,price\n'=SUM(A1),100\n'Go',200\n",
+			want:  []readResult{{Line: 2, Name: "=SUM(A1)"}, {Line: 3, Name: "'Go'"}},
+		},
+		{
+			name:  "価格が数値でない行",
+			input: "name,price\nGo入門,無料\nWeb入門,1980\n",
+			want:  []readResult{{Line: 2, Name: "Go入門", Err: rowFormat}, {Line: 3, Name: "Web入門"}},
+		},
+		{
+			name:  "引用符の誤りはその行のみ失敗する",
+			input: "name,price\nGo\"入門,2800\nWeb入門,1980\n",
+			want:  []readResult{{Line: 2, Err: rowFormat}, {Line: 3, Name: "Web入門"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errCode := readAll(t, newTestReader(t, tt.input, FormatCSV, tt.mapping))
+			if errCode != "" || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("読み込み結果 = %+v (%s), want %+v", got, errCode, tt.want)
+			}
+		})
+	}
+}
+
+// ヘッダーに必要な列がない場合は読み込みを始めない
+func TestCSVReaderHeader(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		mapping map[string]string
+	}{
+		"空のファイル":      {input: ""},
+		"price の列がない": {input: "name\nGo入門\n"},
+		"対応する列がない":    {input: "name,price\n", mapping: map[string]string{"書名": "name"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tt.input), FormatCSV, tt.mapping)
+			var userErr *errors.UserDefinedError
+			if !stderrors.As(err, &userErr) || userErr.ErrorCode != "VAL-ERR-400-34" {
+				t.Errorf("NewReader のエラー = %v, want VAL-ERR-400-34", err)
+			}
+		})
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []readResult
+		errCode string
+	}{
+		{
+			name:  "要素が始まる行",
+			input: "[\n  {\"name\": \"Go入門\",\n   \"price\": 2800},\n\n  {\"name\": \"Web入門\", \"price\": 1980}\n]\n",
+			want:  []readResult{{Line: 2, Name: "Go入門"}, {Line: 5, Name: "Web入門"}},
+		},
+		{
+			name:  "1行の配列",
+			input: `[{"name":"a"},{"name":"b"}]`,
+			want:  []readResult{{Line: 1, Name: "a"}, {Line: 1, Name: "b"}},
+		},
+		{
+			name:  "文字列中の改行のエスケープは行として数えない",
+			input: "[{\"name\":\"a\\nb\"},\n{\"name\":\"c\"}]",
+			want:  []readResult{{Line: 1, Name: "a\nb"}, {Line: 2, Name: "c"}},
+		},
+		{
+			name:  "BOM",
+			input: "\ufeff[\n{\"name\":\"Go入門\"}\n]",
+			want:  []readResult{{Line: 2, Name: "Go入門"}},
+		},
+		{
+			name:  "型が一致しない要素はその要素のみ失敗する",
+			input: "[\n{\"name\":\"a\",\"price\":\"高い\"},\n{\"name\":\"b\"}\n]",
+			want:  []readResult{{Line: 2, Err: rowFormat}, {Line: 3, Name: "b"}},
+		},
+		{
+			name:  "空の配列",
+			input: "[]",
+		},
+		{
+			name:    "配列でない",
+			input:   `{"name":"a"}`,
+			errCode: fileFormat,
+		},
+		{
+			name:    "途中で形式が不正になった場合はそこまでの要素を返す",
+			input:   "[\n{\"name\":\"a\"},\n{\"name\":\n",
+			want:    []readResult{{Line: 2, Name: "a"}},
+			errCode: fileFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errCode := readAll(t, newTestReader(t, tt.input, FormatJSON, nil))
+			if errCode != tt.errCode || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("読み込み結果 = %+v (%s), want %+v (%s)", got, errCode, tt.want, tt.errCode)
+			}
+		})
+	}
+}
+
+// 大きなファイルでも、デコーダーの先読みをまたいで正しい行番号を返す
+func TestJSONReaderLargeInput(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("[\n")
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			b.WriteString(",\n")
+		}
+		b.WriteString(`{"name": "` + strings.Repeat("長", 50) + `"}`)
+	}
+	b.WriteString("\n]\n")
+
+	got, errCode := readAll(t, newTestReader(t, b.String(), FormatJSON, nil))
+	if errCode != "" || len(got) != 2000 {
+		t.Fatalf("読み込み件数 = %d (%s), want 2000", len(got), errCode)
+	}
+	for i, r := range got {
+		if r.Line != i+2 {
+			t.Fatalf("%d件目の行番号 = %d, want %d", i+1, r.Line, i+2)
+		}
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := "\ufeff{\"name\":\"Go入門\"}\n\n   \n{\"name\":\"Web入門\"}\r\nnot json\n{\"name\":\"最後の行\"}"
+	want := []readResult{
+		{Line: 1, Name: "Go入門"},
+		{Line: 4, Name: "Web入門"},
+		{Line: 5, Err: rowFormat},
+		{Line: 6, Name: "最後の行"},
+	}
+	got, errCode := readAll(t, newTestReader(t, input, FormatNDJSON, nil))
+	if errCode != "" || !reflect.DeepEqual(got, want) {
+		t.Errorf("読み込み結果 = %+v (%s), want %+v", got, errCode, want)
+	}
+}
+
+func TestUnescapeCSVCell(t *testing.T) {
+	tests := map[string]string{
+		"":       "",
+		"'":      "'",
+		"'=1+1":  "=1+1",
+		"'+81":   "+81",
+		"'-1":    "-1",
+		"'@user": "@user",
+		"'\tcmd": "\tcmd",
+		"'Go":    "'Go",
+		"''=1":   "''=1",
+		"=1":     "=1",
+		"Go言語":   "Go言語",
+	}
+	for in, want := range tests {
+		if got := unescapeCSVCell(in); got != want {
+			t.Errorf("unescapeCSVCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	got, err := ParseMapping(" 書名 : name , 価格:price,ISBN:isbn")
+	want := map[string]string{"書名": "name", "価格": "price", "ISBN": "isbn"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMapping = %v, %v, want %v", got, err, want)
+	}
+	if got, err := ParseMapping(""); err != nil || len(got) != 0 {
+		t.Errorf("ParseMapping(\"\") = %v, %v, want 空", got, err)
+	}
+	for _, spec := range []string{"書名", "書名:title", ":name", "書名:name,"} {
+		if _, err := ParseMapping(spec); err == nil {
+			t.Errorf("ParseMapping(%q) がエラーになりませんでした", spec)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
+	"github.com/HwaI12/go-api-tutorial/internal/model"
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+)
+
+// 論理削除した本を物理削除するまでの保持期間と、物理削除の実行間隔のデフォルト値
+const (
+	defaultSoftDeleteRetention = 30 * 24 * time.Hour
+	defaultPurgeInterval       = time.Hour
+)
+
+// StartPurgeJob は論理削除した本を定期的に物理削除するジョブを開始する
+// 有効期限を過ぎた Idempotency-Key も同じ間隔で削除する
+// 保持期間は SOFT_DELETE_RETENTION、実行間隔は PURGE_INTERVAL で指定する
+// PURGE_INTERVAL に 0 を指定した場合はジョブを開始しない
+// ctx がキャンセルされるとジョブは終了する
+func StartPurgeJob(ctx context.Context, db *sql.DB) {
+	entry := logger.WithTransaction(ctx)
+	retention := config.GetDuration("SOFT_DELETE_RETENTION", defaultSoftDeleteRetention)
+	interval := config.GetDuration("PURGE_INTERVAL", defaultPurgeInterval)
+	if interval <= 0 {
+		entry.Info("PURGE_INTERVALが0のため、削除済みの本の物理削除ジョブを開始しません")
+		return
+	}
+	entry.Infof("削除済みの本の物理削除ジョブを開始します: retention=%s, interval=%s", retention, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		purge(ctx, db, retention)
+		for {
+			select {
+			case <-ctx.Done():
+				entry.Info("削除済みの本の物理削除ジョブを終了します")
+				return
+			case <-ticker.C:
+				purge(ctx, db, retention)
+			}
+		}
+	}()
+}
+
+// 保持期間を過ぎた削除済みの本と、有効期限を過ぎた Idempotency-Key を削除する
+func purge(ctx context.Context, db *sql.DB, retention time.Duration) {
+	// 実行ごとにトランザクションIDを発行し、ログを追跡できるようにする
+	ctx = transaction.NewTransaction(ctx)
+	entry := logger.WithTransaction(ctx)
+
+	before := time.Now().Add(-retention)
+	purged, err := model.PurgeDeletedBooks(ctx, db, before)
+	if err != nil {
+		// 本の物理削除に失敗しても、Idempotency-Key の削除は続けて行う
+		entry.Errorf("削除済みの本の物理削除に失敗しました: %v", err)
+	} else {
+		metrics.BooksPurgedTotal.Add(float64(purged))
+		if purged > 0 {
+			entry.Infof("削除済みの本を物理削除しました (%d件)", purged)
+		}
+	}
+
+	// 有効期限を過ぎた Idempotency-Key も合わせて削除する
+	expired, err := model.PurgeExpiredIdempotencyKeys(ctx, db)
+	if err != nil {
+		entry.Errorf("期限切れのIdempotency-Keyの削除に失敗しました: %v", err)
+		return
+	}
+	if expired > 0 {
+		entry.Infof("期限切れのIdempotency-Keyを削除しました (%d件)", expired)
+	}
+}
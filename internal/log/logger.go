@@ -48,3 +48,14 @@ func WithTransaction(ctx context.Context) *logrus.Entry {
 		"trn_time": trnTime,
 	})
 }
+
+// DatabaseLogger は pkg/database のログを、トランザクション情報を含めて出力する
+type DatabaseLogger struct{}
+
+func (DatabaseLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	WithTransaction(ctx).Infof(format, args...)
+}
+
+func (DatabaseLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	WithTransaction(ctx).Warnf(format, args...)
+}
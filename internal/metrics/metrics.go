@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+)
+
+// アプリケーション全体で使用するレジストリ
+var DefaultRegistry = NewRegistry()
+
+var (
+	// HTTPリクエスト数 (ルートテンプレート・メソッド・ステータス別)
+	HTTPRequestsTotal = NewCounterVec(
+		"http_requests_total",
+		"処理したHTTPリクエストの数",
+		"route", "method", "status",
+	)
+
+	// HTTPリクエストの処理時間 (秒)
+	HTTPRequestDuration = NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTPリクエストの処理時間(秒)",
+		DefaultBuckets,
+		"route", "method", "status",
+	)
+
+	// エラーレスポンス数 (UserDefinedError のエラーコード別)
+	ErrorsTotal = NewCounterVec(
+		"api_errors_total",
+		"返却したエラーレスポンスの数",
+		"code",
+	)
+
+	// 認証失敗数 (理由別)
+	AuthFailuresTotal = NewCounterVec(
+		"auth_failures_total",
+		"APIキー認証に失敗した回数",
+		"reason",
+	)
+
+	// 登録された本の数
+	BooksCreatedTotal = NewCounterVec(
+		"books_created_total",
+		"登録に成功した本の数",
+	)
+
+	// 保持期間を過ぎて物理削除した本の数
+	BooksPurgedTotal = NewCounterVec(
+		"books_purged_total",
+		"物理削除した削除済みの本の数",
+	)
+)
+
+func init() {
+	DefaultRegistry.Register(HTTPRequestsTotal)
+	DefaultRegistry.Register(HTTPRequestDuration)
+	DefaultRegistry.Register(ErrorsTotal)
+	DefaultRegistry.Register(AuthFailuresTotal)
+	DefaultRegistry.Register(BooksCreatedTotal)
+	DefaultRegistry.Register(BooksPurgedTotal)
+}
+
+// sql.DB のコネクションプール統計を登録する
+// 現在の値はゲージ、累積値 (待機回数・クローズ数など) はカウンターとして登録する
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	stat := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+	r.Register(NewGaugeFunc("db_max_open_connections", "コネクションの最大オープン数",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })))
+	r.Register(NewGaugeFunc("db_open_connections", "オープン中のコネクション数",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) })))
+	r.Register(NewGaugeFunc("db_in_use_connections", "使用中のコネクション数",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) })))
+	r.Register(NewGaugeFunc("db_idle_connections", "アイドル状態のコネクション数",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) })))
+	r.Register(NewCounterFunc("db_wait_count_total", "コネクション取得を待機した回数",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) })))
+	r.Register(NewCounterFunc("db_wait_duration_seconds_total", "コネクション取得の待機時間の合計(秒)",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })))
+	r.Register(NewCounterFunc("db_max_idle_closed_total", "MaxIdleConns によりクローズされたコネクション数",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })))
+	r.Register(NewCounterFunc("db_max_lifetime_closed_total", "ConnMaxLifetime によりクローズされたコネクション数",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })))
+}
+
+// レジストリの内容を Prometheus テキスト形式で返すハンドラー
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var buf bytes.Buffer
+		if err := r.Write(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bufio"
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Prometheus テキスト形式で書き出す
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := NewCounterVec("http_requests_total", "リクエスト数\n(パス別)", "path", "status")
+	requests.Inc("/books/{id}", "200")
+	requests.Add(2, `C:\path "quoted"`+"\n本", "500")
+	r.Register(requests)
+	r.Register(NewGaugeFunc("queue_size", `待機中のジョブ数 \ 件`, func() float64 { return 3 }))
+	r.Register(NewCounterFunc("wait_seconds_total", "待機時間", func() float64 { return 1.5 }))
+	duration := NewHistogramVec("request_duration_seconds", "処理時間", []float64{1, 0.1}, "method")
+	duration.Observe(0.05, "GET")
+	duration.Observe(0.5, "GET")
+	duration.Observe(2, "GET")
+	r.Register(duration)
+
+	var buf strings.Builder
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP http_requests_total リクエスト数\n(パス別)
+# TYPE http_requests_total counter
+http_requests_total{path="/books/{id}",status="200"} 1
+http_requests_total{path="C:\\path \"quoted\"\n本",status="500"} 2
+# HELP queue_size 待機中のジョブ数 \\ 件
+# TYPE queue_size gauge
+queue_size 3
+# HELP wait_seconds_total 待機時間
+# TYPE wait_seconds_total counter
+wait_seconds_total 1.5
+# HELP request_duration_seconds 処理時間
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{method="GET",le="0.1"} 1
+request_duration_seconds_bucket{method="GET",le="1"} 2
+request_duration_seconds_bucket{method="GET",le="+Inf"} 3
+request_duration_seconds_sum{method="GET"} 2.55
+request_duration_seconds_count{method="GET"} 3
+`
+	if got := buf.String(); got != want {
+		t.Errorf("出力が一致しません\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// _total で終わるメトリクスはカウンターとして書き出す
+func TestDBStatsTypes(t *testing.T) {
+	db, err := sql.Open("mysql", "user:password@tcp(127.0.0.1:3306)/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	r := NewRegistry()
+	RegisterDBStats(r, db)
+
+	var buf strings.Builder
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	types := 0
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] != "TYPE" {
+			continue
+		}
+		types++
+		if name, typ := fields[2], fields[3]; strings.HasSuffix(name, "_total") != (typ == "counter") {
+			t.Errorf("%s の型が %s です", name, typ)
+		}
+	}
+	if types != 8 {
+		t.Errorf("メトリクス数 = %d, want 8", types)
+	}
+}
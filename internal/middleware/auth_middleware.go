@@ -3,10 +3,11 @@ package middleware
 import (
 	"context"
 	"net/http"
-	"os"
 
+	"github.com/HwaI12/go-api-tutorial/internal/auth"
 	error "github.com/HwaI12/go-api-tutorial/internal/error"
 	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
 	"github.com/HwaI12/go-api-tutorial/internal/transaction"
 	view "github.com/HwaI12/go-api-tutorial/internal/view"
 	"github.com/sirupsen/logrus"
@@ -24,27 +25,52 @@ func APIKeyAuthMiddleware(next http.Handler) http.Handler {
 		if apiKey == "" {
 			err := error.APIKeyEmptyError()
 			entry.WithError(err).Error("APIキーが空です")
+			metrics.AuthFailuresTotal.Inc("empty")
 			logAndRespondWithError(w, ctx, entry, err)
 			return
 		}
 
 		// APIキーが期待される値と異なる場合はエラーレスポンスを返す
-		expectedAPIKey := os.Getenv("API_KEY")
-		if apiKey != expectedAPIKey {
+		actor, ok := auth.LookupActor(apiKey)
+		if !ok {
 			err := error.InvalidAPIKeyError()
 			entry.WithError(err).Error("APIキーが無効です")
+			metrics.AuthFailuresTotal.Inc("invalid")
 			logAndRespondWithError(w, ctx, entry, err)
 			return
 		}
 
+		// 認証された呼び出し元をコンテキストに設定する
+		next.ServeHTTP(w, r.WithContext(auth.WithActor(ctx, actor)))
+	})
+}
+
+// AdminOnlyMiddleware は管理者以外の呼び出し元を拒否するミドルウェア
+// APIKeyAuthMiddleware の後に使用する
+func AdminOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		entry := logger.WithTransaction(ctx)
+
+		actor := auth.ActorFromContext(ctx)
+		if !auth.IsAdmin(actor) {
+			err := error.AdminRequiredError()
+			entry.WithError(err).Errorf("管理者ではない呼び出し元です: %s", actor)
+			metrics.AuthFailuresTotal.Inc("forbidden")
+			logAndRespondWithError(w, ctx, entry, err)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
 // トランザクション情報をコンテキストに設定するミドルウェア
+// トランザクションIDはリクエストごとに発行する
+// 監査ログに記録するため、リクエストのルートも設定する
 func TransactionMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := transaction.InitializeTransaction(r.Context())
+		ctx := transaction.NewTransaction(r.Context())
+		ctx = transaction.WithRoute(ctx, r.Method+" "+routeTemplate(r, r.URL.Path))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
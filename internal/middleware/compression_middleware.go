@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/andybalholm/brotli"
+)
+
+// 圧縮するレスポンスの最小サイズのデフォルト値 (バイト)
+// これより小さいレスポンスは圧縮しても効果が小さいため、そのまま返す
+const defaultCompressionMinSize = 1024
+
+// 圧縮するレスポンスの Content-Type。"/" で終わるものは前方一致で判定する
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/x-ndjson",
+	"application/msgpack",
+}
+
+// compressor は gzip.Writer と brotli.Writer に共通するインターフェース
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// 対応している圧縮形式。Accept-Encoding で同じ優先度の場合は先にあるものを使用する
+// 書き込み用のバッファが大きいため、Writer はプールして再利用する
+var compressionEncodings = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"br", &sync.Pool{New: func() interface{} {
+		// 動的なレスポンスのため、圧縮率より速度を優先したレベルにする
+		return brotli.NewWriterLevel(nil, 4)
+	}}},
+	{"gzip", &sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}},
+}
+
+// CompressionMiddleware は Accept-Encoding に応じてレスポンスを brotli または gzip で圧縮するミドルウェア
+// COMPRESSION_MIN_SIZE (デフォルト: 1024 バイト) 未満のレスポンスと、圧縮の対象外の Content-Type は圧縮しない
+// 圧縮したレスポンスの ETag は content-coding を付けた強い ETag ("3-abc-br") にする
+// 弱い ETag にすると If-Match (強い比較) に使用できなくなり、圧縮したレスポンスを受け取ったクライアントが楽観ロックを使えないため
+// ストリームで書き出すレスポンスは、最小サイズに達した時点から圧縮しながら書き出す
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding, pool := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if pool == nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			pool:           pool,
+			minSize:        config.GetInt("COMPRESSION_MIN_SIZE", defaultCompressionMinSize),
+			ifNoneMatch:    r.Header.Get("If-None-Match"),
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Accept-Encoding から使用する圧縮形式を選ぶ。圧縮しない場合は nil を返す
+func negotiateEncoding(acceptEncoding string) (string, *sync.Pool) {
+	if acceptEncoding == "" {
+		return "", nil
+	}
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		qualities[name] = q
+	}
+
+	best, bestQ := -1, 0.0
+	for i, e := range compressionEncodings {
+		q, ok := qualities[e.name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	if best < 0 {
+		return "", nil
+	}
+	return compressionEncodings[best].name, compressionEncodings[best].pool
+}
+
+// Content-Type が圧縮の対象かどうかを判定する
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range compressibleTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressResponseWriter は最小サイズに達するまでレスポンスを保持し、
+// 圧縮するかどうかを決めてから書き出す ResponseWriter
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	pool        *sync.Pool
+	minSize     int
+	ifNoneMatch string // 304 で返す ETag を決めるためのリクエストの If-None-Match
+
+	status  int
+	buf     []byte
+	decided bool       // 圧縮するかどうかを決めたか
+	writer  compressor // 圧縮する場合の Writer
+}
+
+func (c *compressResponseWriter) WriteHeader(status int) {
+	if c.decided || c.status != 0 {
+		return
+	}
+	c.status = status
+	// 本文のないレスポンスはそのまま返す
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < 200 {
+		if status == http.StatusNotModified {
+			// クライアントが圧縮した表現をキャッシュしている場合のみ、その ETag を返す
+			encodeETagIfCached(c.Header(), c.encoding, c.ifNoneMatch)
+		}
+		c.passthrough()
+	}
+}
+
+func (c *compressResponseWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.decided {
+		h := c.Header()
+		if h.Get("Content-Encoding") != "" || !isCompressible(h.Get("Content-Type")) {
+			c.passthrough()
+		} else {
+			c.buf = append(c.buf, p...)
+			if len(c.buf) >= c.minSize {
+				c.compress()
+			}
+			return len(p), nil
+		}
+	}
+	if c.writer != nil {
+		return c.writer.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush は保持しているレスポンスを書き出す
+// 最小サイズに達していなくても、ストリームの途中であれば圧縮を開始する
+func (c *compressResponseWriter) Flush() {
+	if !c.decided && c.status != 0 {
+		if isCompressible(c.Header().Get("Content-Type")) && c.Header().Get("Content-Encoding") == "" {
+			c.compress()
+		} else {
+			c.passthrough()
+		}
+	}
+	if c.writer != nil {
+		c.writer.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// 圧縮を開始し、保持していたレスポンスを書き出す
+func (c *compressResponseWriter) compress() {
+	c.decided = true
+	h := c.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", c.encoding)
+	if etag := h.Get("ETag"); etag != "" {
+		h.Set("ETag", view.EncodedETag(etag, c.encoding))
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+
+	c.writer = c.pool.Get().(compressor)
+	c.writer.Reset(c.ResponseWriter)
+	if len(c.buf) > 0 {
+		c.writer.Write(c.buf)
+	}
+	c.buf = nil
+}
+
+// 圧縮せずに、保持していたレスポンスを書き出す
+func (c *compressResponseWriter) passthrough() {
+	c.decided = true
+	c.ResponseWriter.WriteHeader(c.status)
+	if len(c.buf) > 0 {
+		c.ResponseWriter.Write(c.buf)
+	}
+	c.buf = nil
+}
+
+// ハンドラーの終了後に、残りのレスポンスを書き出して Writer をプールに戻す
+func (c *compressResponseWriter) close() {
+	if !c.decided {
+		if c.status == 0 {
+			// ハンドラーが何も書き出していない場合は net/http に任せる
+			return
+		}
+		c.passthrough()
+		return
+	}
+	if c.writer != nil {
+		c.writer.Close()
+		c.writer.Reset(nil)
+		c.pool.Put(c.writer)
+		c.writer = nil
+	}
+}
+
+// 304 のレスポンスの ETag を、If-None-Match に圧縮した表現の ETag が含まれている場合はその ETag にする
+// 圧縮せずに返したレスポンスをキャッシュしているクライアントには、圧縮前の ETag をそのまま返す
+func encodeETagIfCached(h http.Header, encoding, ifNoneMatch string) {
+	etag := h.Get("ETag")
+	if etag == "" || ifNoneMatch == "" {
+		return
+	}
+	encoded := view.EncodedETag(etag, encoding)
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == encoded {
+			h.Set("ETag", encoded)
+			return
+		}
+	}
+}
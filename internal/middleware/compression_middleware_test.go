@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// 指定した Content-Type と本文を返すハンドラーを圧縮のミドルウェアで包む
+func compressionHandler(contentType, body string) http.Handler {
+	return CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"1-abc"`)
+		io.WriteString(w, body)
+	}))
+}
+
+func serveCompression(handler http.Handler, method, acceptEncoding string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/books", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// 圧縮したレスポンスを展開する
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "br":
+		r = brotli.NewReader(body)
+	case "gzip":
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader: %v", err)
+		}
+		r = gr
+	default:
+		r = body
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("展開に失敗しました (%s): %v", encoding, err)
+	}
+	return string(data)
+}
+
+// Accept-Encoding の優先度 (q=0 は拒否) に従って圧縮形式を選ぶ
+func TestCompressionNegotiation(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, br", "br"},
+		{"GZIP", "gzip"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"br; q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0", ""},
+		{"*, br;q=0", "gzip"},
+		{"gzip;q=abc, br;q=0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			w := serveCompression(compressionHandler("application/json", body), http.MethodGet, tt.acceptEncoding)
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+			if got := decompress(t, tt.want, w.Body); got != body {
+				t.Errorf("本文の長さ = %d, want %d", len(got), len(body))
+			}
+		})
+	}
+}
+
+// 最小サイズ未満のレスポンスは圧縮せず、COMPRESSION_MIN_SIZE で最小サイズを変更できる
+func TestCompressionThreshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		minSize string
+		size    int
+		want    string
+	}{
+		{"デフォルトの最小サイズ未満", "", 1023, ""},
+		{"デフォルトの最小サイズ", "", 1024, "gzip"},
+		{"変更した最小サイズ未満", "100", 99, ""},
+		{"変更した最小サイズ", "100", 100, "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COMPRESSION_MIN_SIZE", tt.minSize)
+			body := strings.Repeat("a", tt.size)
+			w := serveCompression(compressionHandler("application/json", body), http.MethodGet, "gzip")
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+			if got := decompress(t, tt.want, w.Body); got != body {
+				t.Errorf("本文の長さ = %d, want %d", len(got), len(body))
+			}
+		})
+	}
+}
+
+// 圧縮の対象外の Content-Type と、圧縮済みのレスポンスはそのまま返す
+func TestCompressionContentTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"application/json; charset=utf-8", "gzip"},
+		{"application/xml", "gzip"},
+		{"application/x-ndjson", "gzip"},
+		{"application/msgpack", "gzip"},
+		{"text/csv; charset=utf-8", "gzip"},
+		{"image/png", ""},
+		{"application/octet-stream", ""},
+		{"", ""},
+	}
+	body := strings.Repeat("a", 2048)
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			w := serveCompression(compressionHandler(tt.contentType, body), http.MethodGet, "gzip")
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	handler := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, body)
+	}))
+	w := serveCompression(handler, http.MethodGet, "gzip")
+	if got := w.Header().Get("Content-Encoding"); got != "br" || w.Body.String() != body {
+		t.Errorf("圧縮済みのレスポンス: Content-Encoding = %q, 本文の長さ = %d", got, w.Body.Len())
+	}
+}
+
+// 圧縮するかどうかにかかわらず Vary: Accept-Encoding を付け、既存の Vary は残す
+func TestCompressionVary(t *testing.T) {
+	handler := func(body string) http.Handler {
+		return CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, body)
+		}))
+	}
+	tests := map[string]struct {
+		acceptEncoding string
+		body           string
+	}{
+		"圧縮する":               {"gzip", strings.Repeat("a", 2048)},
+		"最小サイズ未満":            {"gzip", "{}"},
+		"Accept-Encoding なし": {"", strings.Repeat("a", 2048)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := serveCompression(handler(tt.body), http.MethodGet, tt.acceptEncoding)
+			vary := strings.Join(w.Header().Values("Vary"), ", ")
+			if vary != "Accept-Encoding, Accept" {
+				t.Errorf("Vary = %q, want %q", vary, "Accept-Encoding, Accept")
+			}
+		})
+	}
+}
+
+// HEAD と本文のないレスポンスは圧縮しない
+func TestCompressionWithoutBody(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	w := serveCompression(compressionHandler("application/json", body), http.MethodHead, "gzip")
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("HEAD: Content-Encoding = %q, want 空", got)
+	}
+
+	handler := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	w = serveCompression(handler, http.MethodDelete, "gzip")
+	if w.Code != http.StatusNoContent || w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Errorf("204: %d Content-Encoding = %q, 本文の長さ = %d", w.Code, w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+}
+
+// 圧縮したレスポンスの ETag には圧縮形式を付け、304 では If-None-Match に合わせた ETag を返す
+func TestCompressionETag(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	tests := []struct {
+		name     string
+		encoding string
+		size     int
+		want     string
+	}{
+		{"brotli", "br", 2048, `"1-abc-br"`},
+		{"gzip", "gzip", 2048, `"1-abc-gzip"`},
+		{"圧縮しない", "gzip", 10, `"1-abc"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveCompression(compressionHandler("application/json", body[:tt.size]), http.MethodGet, tt.encoding)
+			if got := w.Header().Get("ETag"); got != tt.want {
+				t.Errorf("ETag = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	notModified := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1-abc"`)
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	notModifiedTests := []struct {
+		ifNoneMatch string
+		want        string
+	}{
+		{`"1-abc-br"`, `"1-abc-br"`},
+		{`"0-xyz", W/"1-abc-br"`, `"1-abc-br"`},
+		{`"1-abc"`, `"1-abc"`},
+		{`"1-abc-gzip"`, `"1-abc"`},
+	}
+	for _, tt := range notModifiedTests {
+		t.Run("304 "+tt.ifNoneMatch, func(t *testing.T) {
+			w := serveCompression(notModified, http.MethodGet, "br", "If-None-Match", tt.ifNoneMatch)
+			if w.Code != http.StatusNotModified || w.Header().Get("ETag") != tt.want || w.Header().Get("Content-Encoding") != "" {
+				t.Errorf("%d ETag = %s Content-Encoding = %q, want 304 ETag = %s", w.Code, w.Header().Get("ETag"), w.Header().Get("Content-Encoding"), tt.want)
+			}
+		})
+	}
+}
+
+// ストリームで書き出すレスポンスは、最小サイズ未満でも Flush した時点で圧縮して書き出す
+func TestCompressionStreamingFlush(t *testing.T) {
+	for _, encoding := range []string{"br", "gzip"} {
+		t.Run(encoding, func(t *testing.T) {
+			var flushed []byte
+			handler := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+				io.WriteString(w, "id,name\n")
+				w.(http.Flusher).Flush()
+				// Flush した時点で、それまでの行がクライアントに届いている
+				flushed = append(flushed, w.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(*httptest.ResponseRecorder).Body.Bytes()...)
+				io.WriteString(w, "1,Go入門\n")
+				w.(http.Flusher).Flush()
+				io.WriteString(w, "2,Web入門\n")
+			}))
+			w := serveCompression(handler, http.MethodGet, encoding)
+
+			if got := w.Header().Get("Content-Encoding"); got != encoding || !w.Flushed {
+				t.Fatalf("Content-Encoding = %q (Flush: %v), want %q", got, w.Flushed, encoding)
+			}
+			if w.Header().Get("Content-Length") != "" {
+				t.Errorf("Content-Length = %q, want 空", w.Header().Get("Content-Length"))
+			}
+			if got := decompressPartial(encoding, flushed); got != "id,name\n" {
+				t.Errorf("最初の Flush までに書き出した内容 = %q, want %q", got, "id,name\n")
+			}
+			if got := decompress(t, encoding, w.Body); got != "id,name\n1,Go入門\n2,Web入門\n" {
+				t.Errorf("本文 = %q", got)
+			}
+		})
+	}
+}
+
+// ストリームの途中までのデータを、読み込めたところまで展開する
+func decompressPartial(encoding string, data []byte) string {
+	var r io.Reader
+	if encoding == "br" {
+		r = brotli.NewReader(strings.NewReader(string(data)))
+	} else {
+		gr, err := gzip.NewReader(strings.NewReader(string(data)))
+		if err != nil {
+			return ""
+		}
+		r = gr
+	}
+	buf := make([]byte, 1024)
+	n, _ := io.ReadAtLeast(r, buf, 1)
+	return string(buf[:n])
+}
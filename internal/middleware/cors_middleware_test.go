@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// プリフライトリクエストにはハンドラーを呼び出さずに 204 を返し、Idempotency-Key を許可する
+func TestCORSPreflight(t *testing.T) {
+	called := false
+	handler := CORSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	r := httptest.NewRequest(http.MethodOptions, "/books", nil)
+	r.Header.Set("Origin", "http://localhost:3000")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	r.Header.Set("Access-Control-Request-Headers", "content-type, idempotency-key, x-api-key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if called || w.Code != http.StatusNoContent {
+		t.Errorf("レスポンス = %d (ハンドラーの呼び出し: %v), want 204", w.Code, called)
+	}
+	if allowed := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, "Idempotency-Key") {
+		t.Errorf("Access-Control-Allow-Headers = %q, want Idempotency-Key を含む", allowed)
+	}
+}
+
+// プリフライト以外のリクエストはハンドラーに渡し、CORS のヘッダーを付ける
+func TestCORSRequest(t *testing.T) {
+	handler := CORSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/books", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("レスポンス = %d %v", w.Code, w.Header())
+	}
+	if exposed := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "Idempotent-Replayed") {
+		t.Errorf("Access-Control-Expose-Headers = %q, want Idempotent-Replayed を含む", exposed)
+	}
+}
@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/auth"
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/model"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+)
+
+// Idempotency-Key の有効期限と、処理中のまま放置されたキーを登録し直すまでの時間のデフォルト値
+const (
+	defaultIdempotencyTTL         = 24 * time.Hour
+	defaultIdempotencyLockTimeout = time.Minute
+	maxIdempotencyKeyLength       = 255
+)
+
+// 保存したレスポンスを返したことを表すレスポンスヘッダー
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// レスポンスを書き出しながら、保存するために内容を記録する ResponseWriter
+// ヘッダーは書き出す前 (外側の CompressionMiddleware が Content-Encoding などを設定する前) の内容を記録する
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+		c.header = c.ResponseWriter.Header().Clone()
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// 保存しないレスポンスヘッダー
+// 圧縮やコンテンツネゴシエーションに関するヘッダーは、再送時に外側のミドルウェアが設定し直す
+var unstoredHeaders = []string{"Content-Encoding", "Content-Length", "Vary"}
+
+// 保存するレスポンスヘッダーを返す
+func storedHeader(header http.Header) http.Header {
+	stored := header.Clone()
+	if stored == nil {
+		stored = http.Header{}
+	}
+	for _, name := range unstoredHeaders {
+		stored.Del(name)
+	}
+	return stored
+}
+
+type idempotencyRecordKey struct{}
+
+// idempotencyRecord は処理中の Idempotency-Key と、レスポンスを保存したかどうか
+type idempotencyRecord struct {
+	db    *sql.DB
+	actor string
+	key   string
+	saved bool // コミットされたトランザクションでレスポンスを保存した
+}
+
+// save はレスポンスを Idempotency-Key に保存する
+// ctx にトランザクションがある場合はそのトランザクションで保存する
+func (rec *idempotencyRecord) save(ctx context.Context, status int, header http.Header, body []byte) error {
+	return model.CompleteIdempotencyKey(ctx, rec.db, rec.actor, rec.key, status, storedHeader(header), body)
+}
+
+// コンテキストから処理中の Idempotency-Key を取得する。Idempotency-Key がない場合は nil を返す
+func idempotencyRecordFromContext(ctx context.Context) *idempotencyRecord {
+	rec, _ := ctx.Value(idempotencyRecordKey{}).(*idempotencyRecord)
+	return rec
+}
+
+// IdempotencyMiddleware は Idempotency-Key ヘッダーが指定された POST リクエストの再試行を検出するミドルウェア
+// 最初のリクエストのレスポンスを IDEMPOTENCY_TTL (デフォルト: 24h) の間保存し、
+// 同じキーで同じリクエストを受け取った場合は処理を行わずに保存したレスポンスを返す
+//   - 同じキーで異なるリクエストを受け取った場合は 422 を返す
+//   - 最初のリクエストを処理中の場合は 409 を返す
+//   - 5xx のレスポンスは保存せず、同じキーで再試行できるようにする
+//
+// キーは呼び出し元ごとに管理するため、APIKeyAuthMiddleware の後に使用する
+// UnitOfWorkMiddleware の前に使用すると、成功したレスポンスは本の登録などと同じトランザクションで保存する
+// (コミットした後にクライアントが切断しても、再試行で二重に処理しない)
+func IdempotencyMiddleware(db *sql.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			entry := logger.WithTransaction(ctx)
+
+			if len(key) > maxIdempotencyKeyLength {
+				err := errors.InvalidIdempotencyKeyError()
+				entry.WithError(err).Errorf("Idempotency-Keyが長すぎます (%d文字)", len(key))
+				view.RespondWithError(w, ctx, err)
+				return
+			}
+
+			// リクエストの比較のためにボディを保持する (ストリームで読み込むルートも MAX_BUFFERED_BODY_SIZE までとなる)
+			body, userErr := readBufferedBody(w, r)
+			if userErr != nil {
+				entry.WithError(userErr).Errorf("リクエストボディの読み込みに失敗しました")
+				view.RespondWithError(w, ctx, userErr)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			actor := auth.ActorFromContext(ctx)
+			fingerprint := requestFingerprint(r, body)
+			ttl := config.GetDuration("IDEMPOTENCY_TTL", defaultIdempotencyTTL)
+			lockTimeout := config.GetDuration("IDEMPOTENCY_LOCK_TIMEOUT", defaultIdempotencyLockTimeout)
+
+			existing, err := model.ReserveIdempotencyKey(ctx, db, actor, key, fingerprint, ttl, lockTimeout)
+			if err != nil {
+				entry.Errorf("Idempotency-Keyの登録に失敗しました: %v", err)
+				respondWithUserError(w, ctx, err)
+				return
+			}
+			if existing != nil {
+				replayIdempotentResponse(w, r, existing, fingerprint)
+				return
+			}
+
+			// クライアントが切断した後も Idempotency-Key の状態を更新できるよう、キャンセルされないコンテキストを使用する
+			bgCtx := context.WithoutCancel(ctx)
+			rec := &idempotencyRecord{db: db, actor: actor, key: key}
+			capture := &responseCapture{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// パニックやレスポンスを保存できなかった場合は、同じキーで再試行できるようにする
+				if !completed {
+					if err := model.ReleaseIdempotencyKey(bgCtx, db, actor, key); err != nil {
+						entry.Errorf("Idempotency-Keyの削除に失敗しました: %v", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(capture, r.WithContext(context.WithValue(ctx, idempotencyRecordKey{}, rec)))
+
+			if rec.saved {
+				// UnitOfWorkMiddleware が本の登録などと同じトランザクションで保存した
+				completed = true
+				return
+			}
+			if capture.status == 0 || capture.status >= http.StatusInternalServerError {
+				entry.Warnf("レスポンスを保存せずにIdempotency-Keyを削除します: status=%d", capture.status)
+				return
+			}
+			// ロールバックした 4xx のレスポンスと、トランザクションを使用しないルートのレスポンスはここで保存する
+			if err := rec.save(bgCtx, capture.status, capture.header, capture.body.Bytes()); err != nil {
+				entry.Errorf("レスポンスの保存に失敗しました: %v", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// 登録済みの Idempotency-Key に対するレスポンスを返す
+func replayIdempotentResponse(w http.ResponseWriter, r *http.Request, existing *model.IdempotencyRecord, fingerprint string) {
+	ctx := r.Context()
+	entry := logger.WithTransaction(ctx)
+
+	if existing.Fingerprint != fingerprint {
+		err := errors.IdempotencyKeyMismatchError()
+		entry.WithError(err).Errorf("Idempotency-Keyが異なるリクエストで使用されました: %s", existing.Key)
+		view.RespondWithError(w, ctx, err)
+		return
+	}
+	if existing.State != model.IdempotencyCompleted {
+		err := errors.IdempotencyKeyInProgressError()
+		entry.WithError(err).Warnf("同じIdempotency-Keyのリクエストを処理中です: %s", existing.Key)
+		w.Header().Set("Retry-After", "1")
+		view.RespondWithError(w, ctx, err)
+		return
+	}
+
+	entry.Infof("保存したレスポンスを返します: key=%s, status=%d", existing.Key, existing.StatusCode)
+	// 以前に保存したレスポンスに Content-Encoding などが含まれていても、圧縮前の本文と一致しないため復元しない
+	for name, values := range storedHeader(existing.Header) {
+		w.Header()[name] = values
+	}
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(existing.StatusCode)
+	w.Write(existing.Body)
+}
+
+// リクエストのメソッド・パス・ボディから、同じリクエストかどうかを判定するためのハッシュを作成する
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// モデルから返されたエラーをレスポンスとして返す
+func respondWithUserError(w http.ResponseWriter, ctx context.Context, err error) {
+	userErr, ok := err.(*errors.UserDefinedError)
+	if !ok {
+		userErr = errors.UnexpectedError()
+	}
+	if errors.IsRequestCanceled(userErr) {
+		return
+	}
+	view.RespondWithError(w, ctx, userErr)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+// ステータスコードを記録するための ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush はストリームで書き出すレスポンスを、内側の ResponseWriter を通してクライアントに送信する
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// リクエスト数と処理時間をメトリクスとして記録するミドルウェア
+// ラベルにはパスではなくルートテンプレートを使用する
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		route := routeTemplate(r, "unknown")
+		status := strconv.Itoa(recorder.status)
+		metrics.HTTPRequestsTotal.Inc(route, r.Method, status)
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), route, r.Method, status)
+	})
+}
+
+// マッチしたルートのテンプレートを返す。取得できない場合は fallback を返す
+func routeTemplate(r *http.Request, fallback string) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return fallback
+}
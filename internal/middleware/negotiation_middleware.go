@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/gorilla/mux"
+)
+
+// NegotiationMiddleware の対象外とするルート
+// ルートの登録時にのみ変更し、リクエストの処理中は読み込みのみ行う
+var noNegotiationRoutes = map[*mux.Route]bool{}
+
+// WithoutNegotiation は指定したルートを NegotiationMiddleware の対象から外す
+// エクスポートのように、レスポンスの形式をハンドラーが独自に決めるルートで使用する
+func WithoutNegotiation(route *mux.Route) *mux.Route {
+	noNegotiationRoutes[route] = true
+	return route
+}
+
+// NegotiationMiddleware は Accept ヘッダーからレスポンスの形式を選び、コンテキストに設定するミドルウェア
+// 対応している形式がない場合は 406 Not Acceptable を JSON で返す
+func NegotiationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil && noNegotiationRoutes[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		w.Header().Add("Vary", "Accept")
+
+		accept := r.Header.Get("Accept")
+		mediaType, ok := view.NegotiateEncoder(accept)
+		if !ok {
+			logger.WithTransaction(ctx).Warnf("対応していない形式が要求されました: Accept=%q", accept)
+			view.RespondWithError(w, ctx, errors.NotAcceptableError(strings.Join(view.EncoderMediaTypes(), ", ")))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(view.WithEncoder(ctx, mediaType)))
+	})
+}
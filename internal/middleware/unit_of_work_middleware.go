@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	view "github.com/HwaI12/go-api-tutorial/internal/view"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+	"github.com/gorilla/mux"
+)
+
+// 再試行などのためにメモリに保持するリクエストボディの最大サイズのデフォルト値 (バイト)
+const defaultMaxBufferedBodySize = 10 << 20
+
+// readBufferedBody はリクエストボディをすべて読み込む
+// MAX_BUFFERED_BODY_SIZE (デフォルト: 10MiB) を超える場合は RequestBodyTooLargeError を返す
+func readBufferedBody(w http.ResponseWriter, r *http.Request) ([]byte, *errors.UserDefinedError) {
+	maxSize := config.GetInt("MAX_BUFFERED_BODY_SIZE", defaultMaxBufferedBodySize)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(maxSize)))
+	r.Body.Close()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			return nil, errors.RequestBodyTooLargeError(maxSize)
+		}
+		return nil, errors.InvalidRequestError()
+	}
+	return body, nil
+}
+
+// ハンドラーがエラーレスポンスを返したためロールバックすることを表すエラー
+var errRollback = stderrors.New("エラーレスポンスのためロールバックします")
+
+// bufferedResponse はコミットが終わるまでレスポンスを保持する ResponseWriter
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: http.Header{}}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// 保持したレスポンスを w に書き出す
+func (b *bufferedResponse) flush(w http.ResponseWriter) {
+	for key, values := range b.header {
+		w.Header()[key] = values
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
+
+// UnitOfWorkMiddleware の対象外とするルート
+// ルートの登録時にのみ変更し、リクエストの処理中は読み込みのみ行う
+var noUnitOfWorkRoutes = map[*mux.Route]bool{}
+
+// WithoutUnitOfWork は指定したルートを UnitOfWorkMiddleware の対象から外す
+// リクエストボディをストリームで読み込み、1件ずつコミットするハンドラーのルートで使用する
+func WithoutUnitOfWork(route *mux.Route) *mux.Route {
+	noUnitOfWorkRoutes[route] = true
+	return route
+}
+
+// UnitOfWorkMiddleware は書き込みリクエストを1つのデータベーストランザクションで処理するミドルウェア
+// ハンドラーが 4xx・5xx のレスポンスを返した場合はロールバックし、それ以外はコミットしてからレスポンスを返す
+// デッドロックで再試行する場合は、リクエストボディを読み直してハンドラーを再度呼び出す
+// GET・HEAD・OPTIONS のリクエストと、WithoutUnitOfWork で指定したルートはトランザクションを開始しない
+func UnitOfWorkMiddleware(db *sql.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if route := mux.CurrentRoute(r); route != nil && noUnitOfWorkRoutes[route] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			entry := logger.WithTransaction(ctx)
+
+			// 再試行のためにリクエストボディを保持する
+			body, userErr := readBufferedBody(w, r)
+			if userErr != nil {
+				entry.WithError(userErr).Errorf("リクエストボディの読み込みに失敗しました")
+				view.RespondWithError(w, ctx, userErr)
+				return
+			}
+
+			rec := idempotencyRecordFromContext(ctx)
+			var response *bufferedResponse
+			err := database.RunInTx(ctx, db, func(txCtx context.Context) error {
+				response = newBufferedResponse()
+				req := r.WithContext(txCtx)
+				req.Body = io.NopCloser(bytes.NewReader(body))
+				next.ServeHTTP(response, req)
+				if response.status >= http.StatusBadRequest {
+					return errRollback
+				}
+				if rec != nil {
+					// Idempotency-Key のレスポンスを同じトランザクションで保存する
+					if err := rec.save(txCtx, response.status, response.header, response.body.Bytes()); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			switch {
+			case err == nil:
+				entry.Infof("トランザクションをコミットしました")
+				if rec != nil {
+					rec.saved = true
+				}
+			case err == errRollback:
+				entry.Infof("エラーレスポンスのためトランザクションをロールバックしました: status=%d", response.status)
+			default:
+				// トランザクションの開始・コミットに失敗した場合、ハンドラーのレスポンスは返さない
+				entry.Errorf("トランザクションの処理に失敗しました: %v", err)
+				if ctx.Err() != nil {
+					entry.Warnf("クライアントが切断されたため、レスポンスを返却しません")
+					return
+				}
+				view.RespondWithError(w, ctx, errors.DatabaseTransactionError())
+				return
+			}
+			response.flush(w)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// fakeDB はトランザクションの開始・コミット・ロールバックの回数を記録するテスト用のデータベース
+type fakeDB struct {
+	mu         sync.Mutex
+	begins     int
+	commits    int
+	rollbacks  int
+	commitErrs []error // コミットのたびに先頭から順に返すエラー
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, stderrors.New("使用しない") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, stderrors.New("使用しない") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.begins++
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (t *fakeTx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.commits++
+	if len(t.db.commitErrs) > 0 {
+		err := t.db.commitErrs[0]
+		t.db.commitErrs = t.db.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.rollbacks++
+	return nil
+}
+
+// テスト用のデータベースと、トランザクションIDを設定したリクエストを作成する
+func newUnitOfWorkTest(t *testing.T, body string) (*fakeDB, *sql.DB, *http.Request) {
+	t.Helper()
+	cfg := database.DefaultConfig()
+	cfg.DeadlockBackoff = time.Millisecond
+	database.Configure(cfg)
+
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.WithValue(context.Background(), transaction.TrnIDKey, "00000000-0000-0000-0000-000000000000")
+	ctx = context.WithValue(ctx, transaction.TrnTimeKey, "2024-04-03T00:00:00Z")
+	r := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(body)).WithContext(ctx)
+	return fake, db, r
+}
+
+// デッドロックでコミットに失敗した場合は、同じリクエストボディでハンドラーを再度呼び出してコミットする
+func TestUnitOfWorkRetriesDeadlock(t *testing.T) {
+	fake, db, r := newUnitOfWorkTest(t, `{"name":"Go"}`)
+	fake.commitErrs = []error{&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}}
+
+	var bodies []string
+	handler := UnitOfWorkMiddleware(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "created")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if len(bodies) != 2 || bodies[0] != `{"name":"Go"}` || bodies[1] != bodies[0] {
+		t.Errorf("ハンドラーに渡したリクエストボディ = %q, want 同じボディで2回", bodies)
+	}
+	if fake.begins != 2 || fake.commits != 2 || fake.rollbacks != 0 {
+		t.Errorf("begin=%d commit=%d rollback=%d, want begin=2 commit=2 rollback=0", fake.begins, fake.commits, fake.rollbacks)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "created" {
+		t.Errorf("レスポンス = %d %q, want 201 \"created\"", w.Code, w.Body.String())
+	}
+}
+
+// ハンドラーが 4xx を返した場合はロールバックし、ハンドラーのレスポンスをそのまま返す
+func TestUnitOfWorkRollsBackErrorResponse(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			fake, db, r := newUnitOfWorkTest(t, `{}`)
+
+			handler := UnitOfWorkMiddleware(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				io.WriteString(w, `{"error":true}`)
+			}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if fake.begins != 1 || fake.commits != 0 || fake.rollbacks != 1 {
+				t.Errorf("begin=%d commit=%d rollback=%d, want begin=1 commit=0 rollback=1", fake.begins, fake.commits, fake.rollbacks)
+			}
+			if w.Code != status || w.Body.String() != `{"error":true}` || w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("レスポンス = %d %q, want %d のハンドラーのレスポンス", w.Code, w.Body.String(), status)
+			}
+		})
+	}
+}
+
+// GET のリクエストはトランザクションを開始しない
+func TestUnitOfWorkSkipsReadRequests(t *testing.T) {
+	fake, db, r := newUnitOfWorkTest(t, "")
+	r.Method = http.MethodGet
+
+	handler := UnitOfWorkMiddleware(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if database.InTx(r.Context()) {
+			t.Error("GET のリクエストでトランザクションを開始しました")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if fake.begins != 0 {
+		t.Errorf("begin=%d, want 0", fake.begins)
+	}
+}
+
+// MAX_BUFFERED_BODY_SIZE を超えるリクエストボディは読み込まずに 413 を返す
+func TestUnitOfWorkRejectsLargeBody(t *testing.T) {
+	t.Setenv("MAX_BUFFERED_BODY_SIZE", "8")
+	fake, db, r := newUnitOfWorkTest(t, `{"name":"too large"}`)
+
+	called := false
+	handler := UnitOfWorkMiddleware(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if called || fake.begins != 0 {
+		t.Errorf("ハンドラーを呼び出しました (begin=%d)", fake.begins)
+	}
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "VAL-ERR-413-00") {
+		t.Errorf("レスポンス = %d %s, want 413 VAL-ERR-413-00", w.Code, w.Body.String())
+	}
+}
+
+// AfterCommit で登録した関数は、コミットに成功した回の分のみ実行する
+func TestUnitOfWorkAfterCommit(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		commitErrs []error
+		want       int
+	}{
+		{"コミット", http.StatusCreated, nil, 1},
+		{"デッドロックで再試行", http.StatusCreated, []error{&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}}, 1},
+		{"エラーレスポンスでロールバック", http.StatusConflict, nil, 0},
+		{"コミットの失敗", http.StatusCreated, []error{stderrors.New("接続が切断されました")}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, db, r := newUnitOfWorkTest(t, `{}`)
+			fake.commitErrs = tt.commitErrs
+
+			calls := 0
+			handler := UnitOfWorkMiddleware(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				database.AfterCommit(r.Context(), func() { calls++ })
+				w.WriteHeader(tt.status)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if calls != tt.want {
+				t.Errorf("コミット後の処理の実行回数 = %d, want %d", calls, tt.want)
+			}
+		})
+	}
+
+	// トランザクションがない場合はすぐに実行する
+	calls := 0
+	database.AfterCommit(context.Background(), func() { calls++ })
+	if calls != 1 {
+		t.Errorf("トランザクションがない場合の実行回数 = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,341 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/auth"
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// 監査ログに記録する操作
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge" // 論理削除した本の物理削除
+	AuditActionMerge   = "merge" // タグの統合
+)
+
+// すべての監査ログの操作
+var AuditActions = []string{AuditActionCreate, AuditActionUpdate, AuditActionDelete, AuditActionRestore,
+	AuditActionPurge, AuditActionMerge}
+
+// 監査ログの対象
+const (
+	AuditEntityBook   = "book"
+	AuditEntityTag    = "tag"
+	AuditEntityAuthor = "author"
+)
+
+// 認証されていない処理 (バックグラウンドジョブなど) の監査ログに記録する呼び出し元
+const systemActor = "system"
+
+// AuditEntry は監査ログの1件
+type AuditEntry struct {
+	ID       string `json:"id"`
+	Actor    string `json:"actor"`
+	TrnID    string `json:"trn_id"`
+	Route    string `json:"route"`
+	Action   string `json:"action"`
+	Entity   string `json:"entity"`
+	EntityID string `json:"entity_id"`
+	// 変更された項目ごとの変更前と変更後の値 ({"price": {"before": 1000, "after": 1200}})
+	Diff      json.RawMessage `json:"diff"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter は監査ログの取得時の絞り込み条件
+type AuditFilter struct {
+	Entity   string
+	EntityID string
+	Actor    string
+	Action   string
+	TrnID    string
+	From     time.Time // 指定した日時以降の監査ログのみ取得する
+	To       time.Time // 指定した日時より前の監査ログのみ取得する
+	Limit    int       // 0 の場合は件数を制限しない
+	Offset   int
+	// 古い順に取得する (デフォルトは新しい順)
+	OldestFirst bool
+}
+
+// IsValidAuditAction は監査ログの操作として正しい値かどうかを判定する
+func IsValidAuditAction(action string) bool {
+	for _, a := range AuditActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// 検索条件から WHERE 句と引数を作成する
+func (f AuditFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if f.Entity != "" {
+		add("entity = ?", f.Entity)
+	}
+	if f.EntityID != "" {
+		add("entity_id = ?", f.EntityID)
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+	if f.TrnID != "" {
+		add("trn_id = ?", f.TrnID)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", f.To.UTC())
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// recordAudit は監査ログを記録する
+// 変更と同じトランザクションで記録するため、tx には変更に使用したトランザクションを渡す
+// before と after は JSON に変換できる値で、登録時の before と物理削除時の after は nil とする
+func recordAudit(ctx context.Context, tx database.DBTX, action, entity, entityID string, before, after interface{}) (err error) {
+	const query = "INSERT INTO audit_log(actor, trn_id, route, action, entity, entity_id, diff) VALUES(?, ?, ?, ?, ?, ?, ?)"
+	ctx, span := startQuerySpan(ctx, "RecordAudit", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		entry.Errorf("監査ログの差分の作成に失敗しました: %v", err)
+		return errors.UnexpectedError()
+	}
+
+	actor := auth.ActorFromContext(ctx)
+	if actor == "" {
+		actor = systemActor
+	}
+	trnID, _ := ctx.Value(transaction.TrnIDKey).(string)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "record_audit")
+	defer cancel()
+
+	if _, err := tx.ExecContext(queryCtx, query, actor, trnID, transaction.RouteFromContext(ctx),
+		action, entity, entityID, string(diff)); err != nil {
+		entry.Errorf("監査ログの登録に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+	}
+	entry.Infof("監査ログを登録しました: %s %s id=%s, actor=%s", action, entity, entityID, actor)
+	return nil
+}
+
+// auditDiff は変更前と変更後の値を比較し、変更された項目の差分を JSON で返す
+func auditDiff(before, after interface{}) ([]byte, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	type change struct {
+		Before json.RawMessage `json:"before"`
+		After  json.RawMessage `json:"after"`
+	}
+	null := json.RawMessage("null")
+	diff := map[string]change{}
+	for _, fields := range []map[string]json.RawMessage{beforeFields, afterFields} {
+		for key := range fields {
+			b, ok := beforeFields[key]
+			if !ok {
+				b = null
+			}
+			a, ok := afterFields[key]
+			if !ok {
+				a = null
+			}
+			if !bytes.Equal(b, a) {
+				diff[key] = change{Before: b, After: a}
+			}
+		}
+	}
+	return json.Marshal(diff)
+}
+
+// 値を JSON に変換し、項目ごとに分割する。nil の場合は空のマップを返す
+func jsonFields(v interface{}) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if v == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// GetAuditLog は監査ログを取得する
+// 絞り込み条件に一致する監査ログの総数も合わせて返す
+func GetAuditLog(ctx context.Context, db *sql.DB, filter AuditFilter) (entries []AuditEntry, total int, err error) {
+	where, args := filter.where()
+	order := " ORDER BY id DESC"
+	if filter.OldestFirst {
+		order = " ORDER BY id"
+	}
+	query := "SELECT " + auditColumns + " FROM audit_log" + where + order
+	pageArgs := args
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		pageArgs = append(append([]interface{}{}, args...), filter.Limit, filter.Offset)
+	}
+	ctx, span := startQuerySpan(ctx, "GetAuditLog", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(entries))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_audit_log")
+	defer cancel()
+
+	if err := database.Conn(ctx, db).QueryRowContext(queryCtx, "SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		entry.Errorf("監査ログの件数の取得に失敗しました: %v", err)
+		return nil, 0, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, pageArgs...)
+	if err != nil {
+		entry.Errorf("監査ログの取得に失敗しました: %v", err)
+		return nil, 0, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	entries = []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := scanAuditEntry(rows, &e); err != nil {
+			entry.Errorf("監査ログのスキャンに失敗しました: %v", err)
+			return nil, 0, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("監査ログの読み込みに失敗しました: %v", err)
+		return nil, 0, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	entry.Infof("監査ログの取得に成功しました (%d件 / 全%d件)", len(entries), total)
+	return entries, total, nil
+}
+
+// EachAuditEntry は filter の条件に一致する監査ログを古い順に1件ずつ取得し、fn を呼び出す
+// すべての監査ログを読み込まずに exportBatchSize 件ずつ取得して渡すため、監査ログのエクスポートに使用する
+// filter の Limit・Offset・OldestFirst は使用しない
+// fn がエラーを返した場合は取得を中断し、そのエラーを返す
+func EachAuditEntry(ctx context.Context, db *sql.DB, filter AuditFilter, fn func(AuditEntry) error) (count int, err error) {
+	where, args := filter.where()
+	// 前回取得した最後の監査ログより後の監査ログを取得する (キーセットページング)
+	if where == "" {
+		where = " WHERE id > ?"
+	} else {
+		where += " AND id > ?"
+	}
+	query := "SELECT " + auditColumns + " FROM audit_log" + where + " ORDER BY id LIMIT ?"
+	ctx, span := startQuerySpan(ctx, "EachAuditEntry", query)
+	defer func() {
+		span.SetAttribute("db.row_count", count)
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "export_audit_log")
+	defer cancel()
+
+	lastID := "0"
+	for {
+		entries, err := eachAuditBatch(ctx, queryCtx, db, query, append(append([]interface{}{}, args...), lastID, exportBatchSize))
+		if err != nil {
+			return count, err
+		}
+		for _, e := range entries {
+			if err := fn(e); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(entries) < exportBatchSize {
+			break
+		}
+		lastID = entries[len(entries)-1].ID
+	}
+	entry.Infof("監査ログを%d件取得しました", count)
+	return count, nil
+}
+
+// EachAuditEntry の1回分の監査ログを取得する
+func eachAuditBatch(ctx, queryCtx context.Context, db *sql.DB, query string, args []interface{}) ([]AuditEntry, error) {
+	entry := logger.WithTransaction(ctx)
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, args...)
+	if err != nil {
+		entry.Errorf("監査ログの取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := scanAuditEntry(rows, &e); err != nil {
+			entry.Errorf("監査ログのスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("監査ログの読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	return entries, nil
+}
+
+// 監査ログの取得で SELECT する列 (scanAuditEntry と同じ順序)
+const auditColumns = "id, actor, trn_id, route, action, entity, entity_id, diff, created_at"
+
+// 監査ログの1行を読み込む
+func scanAuditEntry(rows *sql.Rows, e *AuditEntry) error {
+	var diff []byte
+	if err := rows.Scan(&e.ID, &e.Actor, &e.TrnID, &e.Route, &e.Action, &e.Entity, &e.EntityID,
+		&diff, (*utcTime)(&e.CreatedAt)); err != nil {
+		return err
+	}
+	e.Diff = json.RawMessage(diff)
+	return nil
+}
@@ -0,0 +1,368 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/tracing"
+	"github.com/HwaI12/go-api-tutorial/internal/validation"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+type Author struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuthorInput struct {
+	Name *string `json:"name"`
+}
+
+// Validate は Author モデルの検証を行う
+func (a *Author) Validate(ctx context.Context) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "Author.Validate", tracing.KindInternal)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+	rule := validation.Current().Author.Name
+
+	a.Name = rule.Normalize(a.Name)
+	nameLength := validation.Length(a.Name)
+
+	if a.Name == "" {
+		entry.Errorf("パラメータ'name'が空です。著者の名前を入力してください")
+		return errors.AuthorNameEmptyError()
+	}
+	if rule.MaxLength > 0 && nameLength > rule.MaxLength {
+		entry.Errorf("パラメータ'name'が長すぎます。著者の名前は%d文字以内で書いてください (%d文字)", rule.MaxLength, nameLength)
+		return errors.AuthorNameTooLongError(rule.MaxLength)
+	}
+	if nameLength < rule.MinLength {
+		entry.Errorf("パラメータ'name'が短すぎます。著者の名前は%d文字以上で書いてください (%d文字)", rule.MinLength, nameLength)
+		return errors.AuthorNameTooShortError(rule.MinLength)
+	}
+	entry.Infof("著者の名前が%d文字以上%d文字以内であることを確認しました", rule.MinLength, rule.MaxLength)
+
+	return nil
+}
+
+// GetAuthors はデータベースから著者の一覧を取得する
+func GetAuthors(ctx context.Context, db *sql.DB) (authors []Author, err error) {
+	const query = "SELECT id, name, created_at FROM authors ORDER BY id"
+	ctx, span := startQuerySpan(ctx, "GetAuthors", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(authors))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_authors")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query)
+	if err != nil {
+		entry.Errorf("著者の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	authors = []Author{}
+	for rows.Next() {
+		var author Author
+		if err := rows.Scan(&author.ID, &author.Name, (*utcTime)(&author.CreatedAt)); err != nil {
+			entry.Errorf("著者のスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		authors = append(authors, author)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("著者の読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	entry.Infof("著者の取得に成功しました (%d件)", len(authors))
+	return authors, nil
+}
+
+// GetAuthor は指定したIDの著者を取得する
+func GetAuthor(ctx context.Context, db *sql.DB, id string) (author *Author, err error) {
+	const query = "SELECT id, name, created_at FROM authors WHERE id = ?"
+	ctx, span := startQuerySpan(ctx, "GetAuthor", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_author")
+	defer cancel()
+
+	author = &Author{}
+	err = database.Conn(ctx, db).QueryRowContext(queryCtx, query, id).Scan(&author.ID, &author.Name, (*utcTime)(&author.CreatedAt))
+	if err == sql.ErrNoRows {
+		entry.Warnf("著者が見つかりません: id=%s", id)
+		return nil, errors.AuthorNotFoundError()
+	}
+	if err != nil {
+		entry.Errorf("著者の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+	return author, nil
+}
+
+// CreateAuthor はデータベースに著者を登録する
+func (a *Author) CreateAuthor(ctx context.Context, db *sql.DB) (err error) {
+	const query = "INSERT INTO authors(name) VALUES(?)"
+	ctx, span := startQuerySpan(ctx, "CreateAuthor", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "create_author")
+	defer cancel()
+
+	// LAST_INSERT_ID() は接続ごとの値のため、登録と作成日時の取得は同じトランザクションで行う
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		result, err := tx.ExecContext(queryCtx, query, a.Name)
+		if err != nil {
+			entry.Errorf("著者の登録に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+		lastInsertId, err := result.LastInsertId()
+		if err != nil {
+			entry.Errorf("最後に挿入されたIDの取得に失敗しました: %v", err)
+			return errors.LastInsertIDError()
+		}
+		a.ID = fmt.Sprintf("%d", lastInsertId)
+
+		// 作成日時はデータベースが設定した値を使用する
+		err = tx.QueryRowContext(queryCtx, "SELECT created_at FROM authors WHERE id = LAST_INSERT_ID()").Scan((*utcTime)(&a.CreatedAt))
+		if err != nil {
+			entry.Errorf("作成日時の取得に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseSelectError())
+		}
+		return recordAudit(ctx, tx, AuditActionCreate, AuditEntityAuthor, a.ID, nil, authorSnapshot{Name: a.Name})
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("著者の登録に成功しました: id=%s", a.ID)
+	return nil
+}
+
+// UpdateAuthor は著者の名前を更新する
+func (a *Author) UpdateAuthor(ctx context.Context, db *sql.DB) (err error) {
+	const query = "UPDATE authors SET name = ? WHERE id = ?"
+	ctx, span := startQuerySpan(ctx, "UpdateAuthor", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "update_author")
+	defer cancel()
+
+	// 更新件数は値が変わらない行を含まないため、著者が存在するかどうかは更新前の状態の取得で確認する
+	// (取得してから更新するまでの間に他のリクエストで削除された場合は 404 を返す)
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		before, err := loadAuthorSnapshot(ctx, tx, a.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(queryCtx, query, a.Name, a.ID); err != nil {
+			entry.Errorf("著者の更新に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+		}
+		return recordAudit(ctx, tx, AuditActionUpdate, AuditEntityAuthor, a.ID, before, authorSnapshot{Name: a.Name})
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("著者の更新に成功しました: id=%s", a.ID)
+	return nil
+}
+
+// DeleteAuthor は著者を削除する。本との関連も合わせて削除される
+func DeleteAuthor(ctx context.Context, db *sql.DB, id string) (err error) {
+	const query = "DELETE FROM authors WHERE id = ?"
+	ctx, span := startQuerySpan(ctx, "DeleteAuthor", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "delete_author")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		before, err := loadAuthorSnapshot(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(queryCtx, query, id); err != nil {
+			entry.Errorf("著者の削除に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+		}
+		return recordAudit(ctx, tx, AuditActionDelete, AuditEntityAuthor, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("著者の削除に成功しました: id=%s", id)
+	return nil
+}
+
+// authorSnapshot は監査ログに記録する著者の状態
+type authorSnapshot struct {
+	Name string `json:"name"`
+}
+
+// loadAuthorSnapshot はトランザクション内で著者の現在の状態を取得する
+// 変更が終わるまで他のトランザクションから変更されないよう、著者の行をロックする
+// 著者が存在しない場合は AuthorNotFoundError を返す
+func loadAuthorSnapshot(ctx context.Context, tx database.DBTX, id string) (*authorSnapshot, error) {
+	const query = "SELECT name FROM authors WHERE id = ? FOR UPDATE"
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "load_author_snapshot")
+	defer cancel()
+
+	snapshot := &authorSnapshot{}
+	err := tx.QueryRowContext(queryCtx, query, id).Scan(&snapshot.Name)
+	if err == sql.ErrNoRows {
+		entry.Warnf("著者が見つかりません: id=%s", id)
+		return nil, errors.AuthorNotFoundError()
+	}
+	if err != nil {
+		entry.Errorf("著者の状態の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+	return snapshot, nil
+}
+
+// getAuthorsByBookIDs は本ごとの著者の一覧を取得する
+func getAuthorsByBookIDs(ctx context.Context, db *sql.DB, bookIDs []string) (authors map[string][]Author, err error) {
+	authors = map[string][]Author{}
+	if len(bookIDs) == 0 {
+		return authors, nil
+	}
+
+	query := "SELECT ba.book_id, a.id, a.name, a.created_at FROM book_authors ba " +
+		"JOIN authors a ON a.id = ba.author_id " +
+		"WHERE ba.book_id IN (" + placeholders(len(bookIDs)) + ") ORDER BY ba.book_id, a.id"
+	ctx, span := startQuerySpan(ctx, "GetAuthorsByBookIDs", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(authors))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_authors_by_book_ids")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, stringArgs(bookIDs)...)
+	if err != nil {
+		entry.Errorf("本の著者の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID string
+		var author Author
+		if err := rows.Scan(&bookID, &author.ID, &author.Name, (*utcTime)(&author.CreatedAt)); err != nil {
+			entry.Errorf("本の著者のスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		authors[bookID] = append(authors[bookID], author)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("本の著者の読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	return authors, nil
+}
+
+// setBookAuthors は本に関連付ける著者を置き換える
+// 存在しない著者IDが含まれている場合はエラーを返す
+func setBookAuthors(ctx context.Context, db database.DBTX, bookID string, authorIDs []string) (err error) {
+	const query = "INSERT INTO book_authors(book_id, author_id) VALUES(?, ?)"
+	ctx, span := startQuerySpan(ctx, "SetBookAuthors", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "set_book_authors")
+	defer cancel()
+
+	authorIDs = uniqueStrings(authorIDs)
+	if len(authorIDs) > 0 {
+		var count int
+		err := database.Conn(ctx, db).QueryRowContext(queryCtx,
+			"SELECT COUNT(*) FROM authors WHERE id IN ("+placeholders(len(authorIDs))+")",
+			stringArgs(authorIDs)...).Scan(&count)
+		if err != nil {
+			entry.Errorf("著者の存在確認に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseSelectError())
+		}
+		if count != len(authorIDs) {
+			entry.Errorf("存在しない著者IDが指定されました: %v", authorIDs)
+			return errors.UnknownAuthorIDError()
+		}
+	}
+
+	if _, err := database.Conn(ctx, db).ExecContext(queryCtx, "DELETE FROM book_authors WHERE book_id = ?", bookID); err != nil {
+		entry.Errorf("本と著者の関連の削除に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+	}
+	for _, authorID := range authorIDs {
+		if _, err := database.Conn(ctx, db).ExecContext(queryCtx, query, bookID, authorID); err != nil {
+			entry.Errorf("本と著者の関連の登録に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+	}
+	entry.Infof("本と著者の関連を更新しました: book_id=%s, author_ids=%v", bookID, authorIDs)
+	return nil
+}
+
+// "?, ?, ?" のようなプレースホルダーを n 個作成する
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// 順序を保ったまま重複を取り除く
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
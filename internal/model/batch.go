@@ -0,0 +1,33 @@
+package model
+
+// バッチの実行方法
+const (
+	BatchModeAtomic     = "atomic"      // すべての操作が成功した場合のみ反映する
+	BatchModeBestEffort = "best_effort" // 成功した操作のみ反映する
+)
+
+// バッチで実行できる操作
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// BookBatchInput は本をまとめて登録・更新・削除するリクエスト
+type BookBatchInput struct {
+	Mode       *string              `json:"mode"`
+	Operations []BookBatchOperation `json:"operations"`
+}
+
+// BookBatchOperation はバッチ内の1件の操作
+// create では book、update では id と book (version を含む)、delete では id を指定する
+type BookBatchOperation struct {
+	Op   *string    `json:"op"`
+	ID   *string    `json:"id"`
+	Book *BookInput `json:"book"`
+}
+
+// IsValidBatchMode はバッチの実行方法として正しい値かどうかを判定する
+func IsValidBatchMode(mode string) bool {
+	return mode == BatchModeAtomic || mode == BatchModeBestEffort
+}
@@ -4,129 +4,906 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/HwaI12/go-api-tutorial/internal/auth"
 	errors "github.com/HwaI12/go-api-tutorial/internal/error"
 	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/tracing"
+	"github.com/HwaI12/go-api-tutorial/internal/validation"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
 )
 
 type Book struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Price     int       `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Price  int    `json:"price"`
+	ISBN   string `json:"isbn,omitempty"` // ISBN-13 に正規化した値。未設定の場合は空文字
+	Status string `json:"status"`
+	// 最後にステータスを変更した日時と変更者。変更されていない場合はゼロ値
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	StatusChangedBy string    `json:"status_changed_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// 楽観的排他制御のためのバージョン。UpdateBook では更新前のバージョンとして扱う
+	Version int `json:"version"`
+	// 論理削除した日時。削除されていない場合はゼロ値
+	DeletedAt time.Time `json:"deleted_at"`
+	// 関連付ける著者のID。nil の場合は更新時に既存の関連を変更しない
+	AuthorIDs []string `json:"author_ids,omitempty"`
+	Authors   []Author `json:"authors,omitempty"`
+	// 本に付けるタグの名前。nil の場合は更新時に既存のタグを変更しない
+	Tags []string `json:"tags,omitempty"`
+	// これまでの最安値。GetBook で取得した場合のみ設定される
+	LowestPrice int `json:"lowest_price,omitempty"`
 }
 
 type BookInput struct {
-	Name  *string `json:"name"`
-	Price *int    `json:"price"`
+	Name      *string   `json:"name"`
+	Price     *int      `json:"price"`
+	ISBN      *string   `json:"isbn"`
+	AuthorIDs *[]string `json:"author_ids"`
+	Tags      *[]string `json:"tags"`
+	Version   *int      `json:"version"` // 更新時に必要な、更新前のバージョン
+}
+
+// NewBookFromInput はリクエストの内容から登録する本を作成する
+// 必須のパラメータ (name, price) がない場合はエラーを返す
+func NewBookFromInput(ctx context.Context, input BookInput) (*Book, error) {
+	entry := logger.WithTransaction(ctx)
+
+	// パラメータの存在チェック
+	if input.Name == nil {
+		entry.Errorf("パラメータ'name'がありません")
+		return nil, errors.ParamNameMissingError()
+	}
+	if input.Price == nil {
+		entry.Errorf("パラメータ'price'がありません")
+		return nil, errors.ParamPriceMissingError()
+	}
+
+	book := &Book{
+		Name:  *input.Name,
+		Price: *input.Price,
+	}
+	book.ApplyInput(input)
+	return book, nil
+}
+
+// ApplyInput はリクエストに含まれるパラメータのみ本に上書きする
+// 著者とタグは指定されていない場合、既存の関連を変更しないよう nil にする
+func (b *Book) ApplyInput(input BookInput) {
+	if input.Name != nil {
+		b.Name = *input.Name
+	}
+	if input.Price != nil {
+		b.Price = *input.Price
+	}
+	b.AuthorIDs = nil
+	b.Tags = nil
+	if input.ISBN != nil {
+		b.ISBN = *input.ISBN
+	}
+	if input.AuthorIDs != nil {
+		b.AuthorIDs = *input.AuthorIDs
+	}
+	if input.Tags != nil {
+		b.Tags = *input.Tags
+	}
+}
+
+// タグによる絞り込みの方法
+const (
+	TagModeAny = "any" // いずれかのタグが付いた本
+	TagModeAll = "all" // すべてのタグが付いた本
+)
+
+// BookFilter は本の一覧取得時の絞り込み条件
+type BookFilter struct {
+	AuthorID     string   // 指定した著者の本のみ取得する
+	ISBN         string   // 指定したISBN (ISBN-13) の本のみ取得する
+	Status       string   // 指定した購入ステータスの本のみ取得する
+	Tags         []string // 指定したタグが付いた本のみ取得する
+	TagMode      string   // Tags の絞り込み方法 (TagModeAny / TagModeAll)
+	EmbedAuthors bool     // 著者の情報を含める
+	EmbedTags    bool     // タグを含める
+	// 論理削除した本も取得する
+	IncludeDeleted bool
+}
+
+// 本の取得時に使用するカラム
+const bookColumns = "b.id, b.name, b.price, b.isbn, b.status, b.status_changed_at, b.status_changed_by, b.created_at, b.updated_at, b.version, b.deleted_at"
+
+// 検索条件から WHERE 句と引数を作成する
+func (f BookFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if !f.IncludeDeleted {
+		conditions = append(conditions, "b.deleted_at IS NULL")
+	}
+	if f.AuthorID != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = b.id AND ba.author_id = ?)")
+		args = append(args, f.AuthorID)
+	}
+	if f.ISBN != "" {
+		conditions = append(conditions, "b.isbn = ?")
+		args = append(args, f.ISBN)
+	}
+	if f.Status != "" {
+		conditions = append(conditions, "b.status = ?")
+		args = append(args, f.Status)
+	}
+	if len(f.Tags) > 0 {
+		tagQuery := "SELECT COUNT(DISTINCT t.id) FROM book_tags bt JOIN tags t ON t.id = bt.tag_id " +
+			"WHERE bt.book_id = b.id AND t.name IN (" + placeholders(len(f.Tags)) + ")"
+		if f.TagMode == TagModeAll {
+			conditions = append(conditions, "("+tagQuery+") = ?")
+			args = append(append(args, stringArgs(f.Tags)...), len(f.Tags))
+		} else {
+			conditions = append(conditions, "("+tagQuery+") > 0")
+			args = append(args, stringArgs(f.Tags)...)
+		}
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// rows.Scan と row.Scan の共通インターフェース
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// bookColumns の順に本をスキャンする
+// bookColumns の後に追加したカラムは extra に読み込む
+func scanBook(s scanner, book *Book, extra ...interface{}) error {
+	var isbn, changedBy sql.NullString
+	dest := []interface{}{&book.ID, &book.Name, &book.Price, &isbn,
+		&book.Status, (*utcTime)(&book.StatusChangedAt), &changedBy, (*utcTime)(&book.CreatedAt),
+		(*utcTime)(&book.UpdatedAt), &book.Version, (*utcTime)(&book.DeletedAt)}
+	err := s.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+	book.ISBN = isbn.String
+	book.StatusChangedBy = changedBy.String
+	return nil
+}
+
+// 空文字を NULL として扱う
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
 }
 
 // Validate は Book モデルの検証を行う
-func (b *Book) Validate(ctx context.Context) error {
+func (b *Book) Validate(ctx context.Context) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "Book.Validate", tracing.KindInternal)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 	entry := logger.WithTransaction(ctx)
+	rules := validation.Current().Book
+
+	// 前後の空白を除去し、Unicode正規化した名前で検証・登録する
+	b.Name = rules.Name.Normalize(b.Name)
+	nameLength := validation.Length(b.Name)
 
 	if b.Name == "" {
 		entry.Errorf("パラメータ'name'が空です。本の名前を入力してください")
 		return errors.BookNameEmptyError()
 	}
 
-	if b.Price == 0 {
+	if b.Price == 0 && !rules.Price.AllowZero {
 		entry.Errorf("パラメータ'price'が0です。本の価格を入力してください")
 		return errors.BookPriceEmptyError()
 	}
 
-	if len(b.Name) > 50 {
-		entry.Errorf("パラメータ'name'が長すぎます。50文字以内で書いてください")
-		return errors.BookNameTooLongError()
+	if rules.Name.MaxLength > 0 && nameLength > rules.Name.MaxLength {
+		entry.Errorf("パラメータ'name'が長すぎます。%d文字以内で書いてください (%d文字)", rules.Name.MaxLength, nameLength)
+		return errors.BookNameTooLongError(rules.Name.MaxLength)
+	}
+	if nameLength < rules.Name.MinLength {
+		entry.Errorf("パラメータ'name'が短すぎます。%d文字以上で書いてください (%d文字)", rules.Name.MinLength, nameLength)
+		return errors.BookNameTooShortError(rules.Name.MinLength)
 	}
-	entry.Infof("本の名前が50文字以内であることを確認しました")
+	entry.Infof("本の名前が%d文字以上%d文字以内であることを確認しました", rules.Name.MinLength, rules.Name.MaxLength)
 
 	if b.Price < 0 {
 		entry.Errorf("パラメータ'price'が0以下です。正の整数を入力してください")
 		return errors.BookPriceNegativeError()
 	}
-	entry.Infof("本の価格が0以上であることを確認しました")
+	if b.Price < rules.Price.Min && !(b.Price == 0 && rules.Price.AllowZero) {
+		entry.Errorf("本の価格が低すぎます")
+		return errors.BookPriceTooLowError(rules.Price.Min)
+	}
+	entry.Infof("本の価格が%d円以上であることを確認しました", rules.Price.Min)
 
-	if b.Price > 20000 {
+	if rules.Price.Max > 0 && b.Price > rules.Price.Max {
 		entry.Errorf("本の価格が高すぎます")
-		return errors.BookPriceTooHighError()
+		return errors.BookPriceTooHighError(rules.Price.Max)
+	}
+	entry.Infof("本の価格が%d円以下であることを確認しました", rules.Price.Max)
+
+	if b.ISBN != "" {
+		isbn, userErr := NormalizeISBN(b.ISBN)
+		if userErr != nil {
+			entry.Errorf("ISBNが不正です: %s (%v)", b.ISBN, userErr)
+			return userErr
+		}
+		b.ISBN = isbn
+		entry.Infof("ISBNが正しいことを確認しました: %s", b.ISBN)
+	}
+
+	if b.Tags != nil {
+		tags, err := normalizeTagNames(ctx, b.Tags)
+		if err != nil {
+			return err
+		}
+		b.Tags = tags
+		entry.Infof("タグが正しいことを確認しました")
 	}
-	entry.Infof("本の価格が20000円以下であることを確認しました")
 
 	return nil
 }
 
-func GetBooks(ctx context.Context, db *sql.DB) ([]Book, error) {
+func GetBooks(ctx context.Context, db *sql.DB, filter BookFilter) (books []Book, err error) {
+	where, args := filter.where()
+	query := "SELECT " + bookColumns + " FROM books b" + where + " ORDER BY b.id"
+	ctx, span := startQuerySpan(ctx, "GetBooks", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(books))
+		span.SetError(err)
+		span.End()
+	}()
 	entry := logger.WithTransaction(ctx)
 
 	entry.Infof("GetBooks関数が呼び出されました")
 
-	rows, err := db.Query("SELECT id, name, price, created_at FROM books")
+	queryCtx, cancel := database.WithTimeout(ctx, "get_books")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, args...)
 	if err != nil {
 		entry.Errorf("データベースからの取得に失敗しました: %v", err)
-		return nil, errors.DatabaseQueryError()
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
 	}
 	entry.Infof("データベースからの取得に成功しました")
 	defer rows.Close()
 
-	books := []Book{}
+	books = []Book{}
 	for rows.Next() {
 		var book Book
-		var createdAt string
-		err := rows.Scan(&book.ID, &book.Name, &book.Price, &createdAt)
+		err := scanBook(rows, &book)
 		if err != nil {
 			entry.Errorf("データベース結果のスキャンに失敗しました: %v", err)
-			return nil, errors.DatabaseScanError()
-		}
-
-		// 文字列からtime.Timeへの変換
-		book.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAt)
-		if err != nil {
-			entry.Errorf("作成日時の変換に失敗しました: %v", err)
-			return nil, errors.DatabaseScanError()
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
 		}
 
 		books = append(books, book)
 	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("データベース結果の読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
 	entry.Infof("データベース結果のスキャンに成功しました")
 
+	if err := embedRelations(ctx, db, books, filter.EmbedAuthors, filter.EmbedTags); err != nil {
+		return nil, err
+	}
+
 	entry.Infof("GetBooks関数が終了しました")
 	return books, nil
 }
 
-// CreateBook はデータベースに書籍を登録する
-func (b *Book) CreateBook(ctx context.Context, db *sql.DB) error {
+// エクスポートで一度に取得する本の件数
+// 著者ID・タグはこの件数ごとにまとめて取得する (GROUP_CONCAT は group_concat_max_len で切り詰められるため使用しない)
+const exportBatchSize = 500
+
+// EachBook は GetBooks と同じ条件・順序で本を1件ずつ取得し、fn を呼び出す
+// すべての本を読み込まずに exportBatchSize 件ずつ取得して渡すため、大量の本のエクスポートに使用する
+// 著者ID・タグは filter.EmbedAuthors・EmbedTags に関係なく常に設定する
+// fn がエラーを返した場合は取得を中断し、そのエラーを返す
+func EachBook(ctx context.Context, db *sql.DB, filter BookFilter, fn func(Book) error) (count int, err error) {
+	where, args := filter.where()
+	// 前回取得した最後の本より後の本を取得する (キーセットページング)
+	if where == "" {
+		where = " WHERE b.id > ?"
+	} else {
+		where += " AND b.id > ?"
+	}
+	query := "SELECT " + bookColumns + " FROM books b" + where + " ORDER BY b.id LIMIT ?"
+	ctx, span := startQuerySpan(ctx, "EachBook", query)
+	defer func() {
+		span.SetAttribute("db.row_count", count)
+		span.SetError(err)
+		span.End()
+	}()
 	entry := logger.WithTransaction(ctx)
 
-	entry.Infof("CreateBook関数が呼び出されました")
+	queryCtx, cancel := database.WithTimeout(ctx, "export_books")
+	defer cancel()
 
-	stmt, err := db.Prepare("INSERT INTO books(name, price) VALUES(?, ?)")
+	lastID := "0"
+	for {
+		books, err := eachBookBatch(ctx, queryCtx, db, query, append(append([]interface{}{}, args...), lastID, exportBatchSize))
+		if err != nil {
+			return count, err
+		}
+		if len(books) == 0 {
+			break
+		}
+		if err := embedRelations(ctx, db, books, true, true); err != nil {
+			return count, err
+		}
+		for _, book := range books {
+			book.AuthorIDs = make([]string, len(book.Authors))
+			for i, a := range book.Authors {
+				book.AuthorIDs[i] = a.ID
+			}
+			book.Authors = nil
+			if err := fn(book); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(books) < exportBatchSize {
+			break
+		}
+		lastID = books[len(books)-1].ID
+	}
+	entry.Infof("本を%d件取得しました", count)
+	return count, nil
+}
+
+// eachBookBatch は EachBook の1回分の本を取得する
+// 著者・タグを取得する前に結果を閉じるため、トランザクション内でも同じ接続で続けてクエリを実行できる
+func eachBookBatch(ctx, queryCtx context.Context, db *sql.DB, query string, args []interface{}) ([]Book, error) {
+	entry := logger.WithTransaction(ctx)
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, args...)
 	if err != nil {
-		entry.Errorf("SQLステートメントの準備に失敗しました: %v", err)
-		return errors.SQLPreparationError()
+		entry.Errorf("データベースからの取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
 	}
-	entry.Infof("SQLステートメントの準備に成功しました")
-	defer stmt.Close()
+	defer rows.Close()
 
-	result, err := stmt.Exec(b.Name, b.Price)
+	var books []Book
+	for rows.Next() {
+		var book Book
+		if err := scanBook(rows, &book); err != nil {
+			entry.Errorf("データベース結果のスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("データベース結果の読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	return books, nil
+}
+
+// GetBook は指定したIDの本を著者・タグの情報とこれまでの最安値を含めて取得する
+// 論理削除した本は BookNotFoundError を返す
+func GetBook(ctx context.Context, db *sql.DB, id string) (book *Book, err error) {
+	// 価格の変更履歴がない本は現在の価格を最安値とする
+	query := "SELECT " + bookColumns + ", " +
+		"LEAST(b.price, COALESCE((SELECT MIN(ph.new_price) FROM price_history ph WHERE ph.book_id = b.id), b.price)) " +
+		"FROM books b WHERE b.id = ? AND b.deleted_at IS NULL"
+	ctx, span := startQuerySpan(ctx, "GetBook", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_book")
+	defer cancel()
+
+	book = &Book{}
+	err = scanBook(database.Conn(ctx, db).QueryRowContext(queryCtx, query, id), book, &book.LowestPrice)
+	if err == sql.ErrNoRows {
+		entry.Warnf("本が見つかりません: id=%s", id)
+		return nil, errors.BookNotFoundError()
+	}
 	if err != nil {
-		entry.Errorf("データベースへの挿入に失敗しました: %v", err)
-		return errors.DatabaseInsertError()
+		entry.Errorf("本の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
 	}
-	entry.Infof("データベースへの挿入に成功しました")
 
-	lastInsertId, err := result.LastInsertId()
+	books := []Book{*book}
+	if err := embedRelations(ctx, db, books, true, true); err != nil {
+		return nil, err
+	}
+	return &books[0], nil
+}
+
+// LockBook は更新が終わるまで他のトランザクションから変更されないよう、本の行をロックする
+// トランザクションの外で呼び出した場合は本の存在確認のみ行う
+func LockBook(ctx context.Context, db *sql.DB, id string) (err error) {
+	const query = "SELECT id FROM books WHERE id = ? AND deleted_at IS NULL FOR UPDATE"
+	ctx, span := startQuerySpan(ctx, "LockBook", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "lock_book")
+	defer cancel()
+
+	var locked string
+	err = database.Conn(ctx, db).QueryRowContext(queryCtx, query, id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		entry.Warnf("本が見つかりません: id=%s", id)
+		return errors.BookNotFoundError()
+	}
 	if err != nil {
-		entry.Errorf("最後に挿入されたIDの取得に失敗しました: %v", err)
-		return errors.LastInsertIDError()
+		entry.Errorf("本のロックに失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+	return nil
+}
+
+// 本の一覧に著者とタグの情報を設定する
+func embedRelations(ctx context.Context, db *sql.DB, books []Book, withAuthors, withTags bool) error {
+	if len(books) == 0 || (!withAuthors && !withTags) {
+		return nil
+	}
+	ids := make([]string, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+	}
+
+	if withAuthors {
+		authors, err := getAuthorsByBookIDs(ctx, db, ids)
+		if err != nil {
+			return err
+		}
+		for i := range books {
+			books[i].Authors = authors[books[i].ID]
+			if books[i].Authors == nil {
+				books[i].Authors = []Author{}
+			}
+		}
+	}
+
+	if withTags {
+		tags, err := getTagsByBookIDs(ctx, db, ids)
+		if err != nil {
+			return err
+		}
+		for i := range books {
+			books[i].Tags = tags[books[i].ID]
+			if books[i].Tags == nil {
+				books[i].Tags = []string{}
+			}
+		}
 	}
-	entry.Infof("最後に挿入されたIDの取得に成功しました")
+	return nil
+}
+
+// CreateBook はデータベースに書籍を登録する
+// 著者・タグとの関連、価格の変更履歴、監査ログも同じトランザクションで登録する
+func (b *Book) CreateBook(ctx context.Context, db *sql.DB) (err error) {
+	const query = "INSERT INTO books(name, price, isbn) VALUES(?, ?, ?)"
+	ctx, span := startQuerySpan(ctx, "CreateBook", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	entry.Infof("CreateBook関数が呼び出されました")
+
+	queryCtx, cancel := database.WithTimeout(ctx, "create_book")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		stmt, err := tx.PrepareContext(queryCtx, query)
+		if err != nil {
+			entry.Errorf("SQLステートメントの準備に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.SQLPreparationError())
+		}
+		entry.Infof("SQLステートメントの準備に成功しました")
+		defer stmt.Close()
 
-	b.ID = fmt.Sprintf("%d", lastInsertId)
-	b.CreatedAt = time.Now()
+		result, err := stmt.ExecContext(queryCtx, b.Name, b.Price, nullString(b.ISBN))
+		if err != nil {
+			if database.IsDuplicateKeyError(err) {
+				entry.Warnf("同じISBNの本が既に登録されています: %s", b.ISBN)
+				return errors.DuplicateISBNError()
+			}
+			entry.Errorf("データベースへの挿入に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+		entry.Infof("データベースへの挿入に成功しました")
+		if affected, err := result.RowsAffected(); err == nil {
+			span.SetAttribute("db.row_count", affected)
+		}
+
+		lastInsertId, err := result.LastInsertId()
+		if err != nil {
+			entry.Errorf("最後に挿入されたIDの取得に失敗しました: %v", err)
+			return errors.LastInsertIDError()
+		}
+		entry.Infof("最後に挿入されたIDの取得に成功しました")
+
+		b.ID = fmt.Sprintf("%d", lastInsertId)
+
+		// ステータスと作成日時・更新日時はデータベースが設定した値を使用する
+		err = tx.QueryRowContext(queryCtx, "SELECT status, created_at, updated_at, version FROM books WHERE id = ?", lastInsertId).
+			Scan(&b.Status, (*utcTime)(&b.CreatedAt), (*utcTime)(&b.UpdatedAt), &b.Version)
+		if err != nil {
+			entry.Errorf("作成日時の取得に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseSelectError())
+		}
+		entry.Infof("作成日時の取得に成功しました")
+
+		if err := recordPriceChange(ctx, tx, b.ID, nil, b.Price); err != nil {
+			return err
+		}
+		if b.AuthorIDs != nil {
+			if err := setBookAuthors(ctx, tx, b.ID, b.AuthorIDs); err != nil {
+				return err
+			}
+		}
+		if b.Tags != nil {
+			if err := setBookTags(ctx, tx, b.ID, b.Tags); err != nil {
+				return err
+			}
+		}
+		return recordBookAudit(ctx, tx, AuditActionCreate, b.ID, nil)
+	})
+	if err != nil {
+		return err
+	}
 
 	entry.Infof("本の登録に成功しました")
 	entry.Infof("CreateBook関数が終了しました")
 	return nil
 }
+
+// UpdateBook は本の名前・価格・ISBNを更新する
+// AuthorIDs や Tags が nil でない場合は著者やタグとの関連も置き換える
+// Version には更新前のバージョンを指定し、他のリクエストで更新されていた場合は VersionConflictError を返す
+// 更新に成功すると Version は新しいバージョンになる
+func (b *Book) UpdateBook(ctx context.Context, db *sql.DB) (err error) {
+	const query = "UPDATE books SET name = ?, price = ?, isbn = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1 " +
+		"WHERE id = ? AND version = ?"
+	ctx, span := startQuerySpan(ctx, "UpdateBook", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "update_book")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		// 価格の変更履歴と監査ログのため、更新前の状態を取得する
+		before, err := loadBookSnapshot(ctx, tx, b.ID)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.DeletedAt != nil {
+			entry.Warnf("更新する本が見つかりません: id=%s", b.ID)
+			return errors.BookNotFoundError()
+		}
+
+		result, err := tx.ExecContext(queryCtx, query, b.Name, b.Price, nullString(b.ISBN), b.ID, b.Version)
+		if err != nil {
+			if database.IsDuplicateKeyError(err) {
+				entry.Warnf("同じISBNの本が既に登録されています: %s", b.ISBN)
+				return errors.DuplicateISBNError()
+			}
+			entry.Errorf("本の更新に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+		}
+		// バージョンは必ず増えるため、更新した行がない場合はバージョンが一致しなかったことになる
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			entry.Warnf("本が他のリクエストで更新されています: id=%s, version=%d, current=%d", b.ID, b.Version, before.Version)
+			return errors.VersionConflictError(before.Version)
+		}
+		b.Version++
+
+		if before.Price != b.Price {
+			if err := recordPriceChange(ctx, tx, b.ID, &before.Price, b.Price); err != nil {
+				return err
+			}
+		}
+		if b.AuthorIDs != nil {
+			if err := setBookAuthors(ctx, tx, b.ID, b.AuthorIDs); err != nil {
+				return err
+			}
+		}
+		if b.Tags != nil {
+			if err := setBookTags(ctx, tx, b.ID, b.Tags); err != nil {
+				return err
+			}
+		}
+		return recordBookAudit(ctx, tx, AuditActionUpdate, b.ID, before)
+	})
+	if err != nil {
+		return err
+	}
+
+	entry.Infof("本の更新に成功しました: id=%s", b.ID)
+	return nil
+}
+
+// DeleteBook は指定したIDの本を論理削除する
+// 削除した本は PurgeDeletedBooks で物理削除されるまで RestoreBook で復元できる
+func DeleteBook(ctx context.Context, db *sql.DB, id string) (err error) {
+	const query = "UPDATE books SET deleted_at = UTC_TIMESTAMP(), updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ? AND deleted_at IS NULL"
+	ctx, span := startQuerySpan(ctx, "DeleteBook", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "delete_book")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		before, err := loadBookSnapshot(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.DeletedAt != nil {
+			entry.Warnf("削除する本が見つかりません: id=%s", id)
+			return errors.BookNotFoundError()
+		}
+		if _, err := tx.ExecContext(queryCtx, query, id); err != nil {
+			entry.Errorf("本の削除に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+		}
+		return recordBookAudit(ctx, tx, AuditActionDelete, id, before)
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("本を論理削除しました: id=%s", id)
+	return nil
+}
+
+// RestoreBook は論理削除した本を復元する
+func RestoreBook(ctx context.Context, db *sql.DB, id string) (err error) {
+	const query = "UPDATE books SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL"
+	ctx, span := startQuerySpan(ctx, "RestoreBook", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "restore_book")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		before, err := loadBookSnapshot(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.DeletedAt == nil {
+			entry.Warnf("復元する削除済みの本が見つかりません: id=%s", id)
+			return errors.DeletedBookNotFoundError()
+		}
+		if _, err := tx.ExecContext(queryCtx, query, id); err != nil {
+			entry.Errorf("本の復元に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+		}
+		return recordBookAudit(ctx, tx, AuditActionRestore, id, before)
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("本を復元しました: id=%s", id)
+	return nil
+}
+
+// PurgeDeletedBooks は before より前に論理削除した本を物理削除し、削除した件数を返す
+// 著者・タグとの関連や価格の変更履歴は外部キーにより合わせて削除される
+// 物理削除した本ごとに、削除前の状態を監査ログに記録する (呼び出し元は systemActor)
+func PurgeDeletedBooks(ctx context.Context, db *sql.DB, before time.Time) (purged int64, err error) {
+	const query = "SELECT id FROM books WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id FOR UPDATE"
+	ctx, span := startQuerySpan(ctx, "PurgeDeletedBooks", query)
+	defer func() {
+		span.SetAttribute("db.row_count", purged)
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+	// バックグラウンドジョブから実行するため、監査ログにはシステムによる操作として記録する
+	ctx = auth.WithActor(ctx, systemActor)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "purge_deleted_books")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		rows, err := tx.QueryContext(queryCtx, query, before.UTC())
+		if err != nil {
+			entry.Errorf("物理削除する本の取得に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseQueryError())
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				entry.Errorf("物理削除する本のスキャンに失敗しました: %v", err)
+				return contextError(ctx, queryCtx, errors.DatabaseScanError())
+			}
+			ids = append(ids, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			entry.Errorf("物理削除する本の読み込みに失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+
+		for _, id := range ids {
+			snapshot, err := loadBookSnapshot(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if snapshot == nil {
+				continue
+			}
+			if _, err := tx.ExecContext(queryCtx, "DELETE FROM books WHERE id = ?", id); err != nil {
+				entry.Errorf("削除済みの本の物理削除に失敗しました: %v", err)
+				return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+			}
+			if err := recordBookAudit(ctx, tx, AuditActionPurge, id, snapshot); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// bookSnapshot は監査ログに記録する本の状態
+type bookSnapshot struct {
+	Name      string     `json:"name"`
+	Price     int        `json:"price"`
+	ISBN      *string    `json:"isbn"`
+	Status    string     `json:"status"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	Version   int        `json:"version"`
+	AuthorIDs []string   `json:"author_ids"`
+	Tags      []string   `json:"tags"`
+}
+
+// loadBookSnapshot はトランザクション内で本の現在の状態を取得する
+// 更新が終わるまで他のトランザクションから変更されないよう、本の行をロックする
+// 本が存在しない場合は nil を返す。論理削除した本も取得する
+func loadBookSnapshot(ctx context.Context, tx database.DBTX, id string) (snapshot *bookSnapshot, err error) {
+	const query = "SELECT name, price, isbn, status, deleted_at, version FROM books WHERE id = ? FOR UPDATE"
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "load_book_snapshot")
+	defer cancel()
+
+	snapshot = &bookSnapshot{AuthorIDs: []string{}, Tags: []string{}}
+	var isbn sql.NullString
+	var deletedAt time.Time
+	err = tx.QueryRowContext(queryCtx, query, id).
+		Scan(&snapshot.Name, &snapshot.Price, &isbn, &snapshot.Status, (*utcTime)(&deletedAt), &snapshot.Version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		entry.Errorf("本の状態の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+	if isbn.Valid {
+		snapshot.ISBN = &isbn.String
+	}
+	if !deletedAt.IsZero() {
+		snapshot.DeletedAt = &deletedAt
+	}
+
+	lists := []struct {
+		query string
+		dest  *[]string
+	}{
+		{"SELECT author_id FROM book_authors WHERE book_id = ? ORDER BY author_id", &snapshot.AuthorIDs},
+		{"SELECT t.name FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE bt.book_id = ? ORDER BY t.name", &snapshot.Tags},
+	}
+	for _, list := range lists {
+		rows, err := tx.QueryContext(queryCtx, list.query, id)
+		if err != nil {
+			entry.Errorf("本の関連の取得に失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+		}
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				rows.Close()
+				entry.Errorf("本の関連のスキャンに失敗しました: %v", err)
+				return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+			}
+			*list.dest = append(*list.dest, v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			entry.Errorf("本の関連の読み込みに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+	}
+	return snapshot, nil
+}
+
+// recordBookAudit は変更後の本の状態を取得し、変更前の状態との差分を監査ログに記録する
+func recordBookAudit(ctx context.Context, tx database.DBTX, action, id string, before *bookSnapshot) error {
+	after, err := loadBookSnapshot(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	// nil の *bookSnapshot を interface{} に変換すると nil と判定されないため、明示的に nil を渡す
+	var b, a interface{}
+	if before != nil {
+		b = before
+	}
+	if after != nil {
+		a = after
+	}
+	return recordAudit(ctx, tx, action, AuditEntityBook, id, b, a)
+}
+
+// inTx はトランザクション内で fn を実行する
+// コンテキストに既にトランザクションがある場合はそのトランザクションを使用する
+// fn が返したエラーはそのまま返し、トランザクションの開始・コミットの失敗は DatabaseTransactionError に変換する
+func inTx(ctx context.Context, db *sql.DB, fn func(tx database.DBTX) error) error {
+	err := database.RunInTx(ctx, db, func(ctx context.Context) error {
+		return fn(database.Conn(ctx, db))
+	})
+	if err == nil {
+		return nil
+	}
+	if userErr, ok := err.(*errors.UserDefinedError); ok {
+		return userErr
+	}
+	logger.WithTransaction(ctx).Errorf("トランザクションの処理に失敗しました: %v", err)
+	return contextError(ctx, ctx, errors.DatabaseTransactionError())
+}
+
+// コンテキストの状態に応じてデータベースエラーを変換する
+// クライアントがリクエストを中断した場合は RequestCanceledError、
+// 操作のタイムアウトを超えた場合は DatabaseTimeoutError、それ以外は fallback を返す
+func contextError(ctx, queryCtx context.Context, fallback *errors.UserDefinedError) *errors.UserDefinedError {
+	entry := logger.WithTransaction(ctx)
+	switch {
+	case ctx.Err() == context.Canceled:
+		entry.Warnf("クライアントによりリクエストが中断されました")
+		return errors.RequestCanceledError()
+	case queryCtx.Err() == context.DeadlineExceeded:
+		entry.Errorf("データベース処理がタイムアウトしました")
+		return errors.DatabaseTimeoutError()
+	}
+	return fallback
+}
+
+// SQLクエリを囲むスパンを開始する
+func startQuerySpan(ctx context.Context, operation, query string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.StartSpan(ctx, "model."+operation, tracing.KindClient)
+	span.SetAttribute("db.system", "mysql")
+	span.SetAttribute("db.operation", operation)
+	span.SetAttribute("db.statement", query)
+	return ctx, span
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// 予算の対象月のフォーマット
+const BudgetMonthLayout = "2006-01"
+
+// Budget は月ごとの予算と、その消化状況
+type Budget struct {
+	Month  string
+	Amount int
+	// 予算を設定していない月で、環境変数 MONTHLY_BUDGET の値を使用している場合は true
+	IsDefault bool
+	// その月に購入済みになった本の合計金額
+	Spent int
+	// その月に注文済みになった本の合計金額
+	Ordered int
+	// 欲しい本の合計金額
+	Wanted int
+}
+
+type BudgetInput struct {
+	Amount *int `json:"amount"`
+}
+
+// Remaining は予算の残額 (購入済み・注文済みの金額を差し引いた額) を返す
+func (b *Budget) Remaining() int {
+	return b.Amount - b.Spent - b.Ordered
+}
+
+// OverBudget は欲しい本の合計金額が予算の残額を超えているかどうかを返す
+func (b *Budget) OverBudget() bool {
+	return b.Wanted > b.Remaining()
+}
+
+// ParseBudgetMonth は "YYYY-MM" 形式の月を検証し、その月の開始日時 (UTC) を返す
+func ParseBudgetMonth(month string) (time.Time, error) {
+	t, err := time.ParseInLocation(BudgetMonthLayout, month, time.UTC)
+	if err != nil {
+		return time.Time{}, errors.InvalidMonthError()
+	}
+	return t, nil
+}
+
+// GetBudget は指定した月の予算と消化状況を取得する
+// 予算が設定されていない場合は環境変数 MONTHLY_BUDGET を使用し、それもなければ BudgetNotFoundError を返す
+func GetBudget(ctx context.Context, db *sql.DB, month string) (budget *Budget, err error) {
+	const query = "SELECT " +
+		"COALESCE(SUM(CASE WHEN b.status = 'purchased' AND b.status_changed_at >= ? AND b.status_changed_at < ? THEN b.price END), 0), " +
+		"COALESCE(SUM(CASE WHEN b.status = 'ordered' AND b.status_changed_at >= ? AND b.status_changed_at < ? THEN b.price END), 0), " +
+		"COALESCE(SUM(CASE WHEN b.status = 'wanted' THEN b.price END), 0) " +
+		"FROM books b WHERE b.deleted_at IS NULL"
+	ctx, span := startQuerySpan(ctx, "GetBudget", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	start, err := ParseBudgetMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_budget")
+	defer cancel()
+
+	budget = &Budget{Month: month}
+	err = database.Conn(ctx, db).QueryRowContext(queryCtx, "SELECT amount FROM budgets WHERE month = ?", month).Scan(&budget.Amount)
+	if err == sql.ErrNoRows {
+		defaultAmount := config.GetInt("MONTHLY_BUDGET", -1)
+		if defaultAmount < 0 {
+			entry.Warnf("予算が設定されていません: month=%s", month)
+			return nil, errors.BudgetNotFoundError()
+		}
+		budget.Amount = defaultAmount
+		budget.IsDefault = true
+	} else if err != nil {
+		entry.Errorf("予算の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+
+	end := start.AddDate(0, 1, 0)
+	err = database.Conn(ctx, db).QueryRowContext(queryCtx, query, start, end, start, end).
+		Scan(&budget.Spent, &budget.Ordered, &budget.Wanted)
+	if err != nil {
+		entry.Errorf("予算の消化状況の集計に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	entry.Infof("予算を取得しました: month=%s, amount=%d, remaining=%d", month, budget.Amount, budget.Remaining())
+	return budget, nil
+}
+
+// SaveBudget は指定した月の予算を登録または更新する
+func (b *Budget) SaveBudget(ctx context.Context, db *sql.DB) (err error) {
+	const query = "INSERT INTO budgets(month, amount) VALUES(?, ?) ON DUPLICATE KEY UPDATE amount = VALUES(amount)"
+	ctx, span := startQuerySpan(ctx, "SaveBudget", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	if b.Amount < 0 {
+		entry.Errorf("予算が負の値です: %d", b.Amount)
+		return errors.BudgetAmountNegativeError()
+	}
+
+	queryCtx, cancel := database.WithTimeout(ctx, "save_budget")
+	defer cancel()
+
+	if _, err := database.Conn(ctx, db).ExecContext(queryCtx, query, b.Month, b.Amount); err != nil {
+		entry.Errorf("予算の保存に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+	}
+	entry.Infof("予算を保存しました: month=%s, amount=%d", b.Month, b.Amount)
+	return nil
+}
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// Idempotency-Key の処理状態
+const (
+	IdempotencyInProgress = "in_progress" // 最初のリクエストを処理中
+	IdempotencyCompleted  = "completed"   // レスポンスを保存済み
+)
+
+// IdempotencyRecord は Idempotency-Key で受け付けたリクエストと保存したレスポンス
+type IdempotencyRecord struct {
+	Actor       string
+	Key         string
+	Fingerprint string // リクエストのメソッド・パス・ボディのハッシュ
+	State       string
+	StatusCode  int
+	Header      map[string][]string
+	Body        []byte
+	LockedAt    time.Time
+	ExpiresAt   time.Time
+}
+
+// ReserveIdempotencyKey は Idempotency-Key を処理中として登録する
+// 登録できた場合は nil を、既に同じキーが登録されている場合はその内容を返す
+// 有効期限を過ぎたキーと、lockTimeout を過ぎても処理中のままの同じリクエストのキーは登録し直す
+// 同時に同じキーで呼び出された場合も、登録できるのは1つのリクエストだけになる
+func ReserveIdempotencyKey(ctx context.Context, db *sql.DB, actor, key, fingerprint string, ttl, lockTimeout time.Duration) (existing *IdempotencyRecord, err error) {
+	const query = "INSERT INTO idempotency_keys(actor, idem_key, fingerprint, state, locked_at, expires_at) " +
+		"VALUES(?, ?, ?, 'in_progress', ?, ?)"
+	ctx, span := startQuerySpan(ctx, "ReserveIdempotencyKey", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "reserve_idempotency_key")
+	defer cancel()
+
+	conn := database.Conn(ctx, db)
+	// 登録済みのキーを取得する間に削除された場合に備えて、登録をもう一度試みる
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now().UTC()
+		_, err := conn.ExecContext(queryCtx, query, actor, key, fingerprint, now, now.Add(ttl))
+		if err == nil {
+			entry.Infof("Idempotency-Keyを登録しました: %s", key)
+			return nil, nil
+		}
+		if !database.IsDuplicateKeyError(err) {
+			entry.Errorf("Idempotency-Keyの登録に失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+
+		result, err := conn.ExecContext(queryCtx,
+			"UPDATE idempotency_keys SET fingerprint = ?, state = 'in_progress', status_code = NULL, "+
+				"response_header = NULL, response_body = NULL, locked_at = ?, expires_at = ? "+
+				"WHERE actor = ? AND idem_key = ? AND (expires_at < ? OR "+
+				"(state = 'in_progress' AND locked_at < ? AND fingerprint = ?))",
+			fingerprint, now, now.Add(ttl), actor, key, now, now.Add(-lockTimeout), fingerprint)
+		if err != nil {
+			entry.Errorf("Idempotency-Keyの再登録に失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+			entry.Infof("期限切れのIdempotency-Keyを登録し直しました: %s", key)
+			return nil, nil
+		}
+
+		existing = &IdempotencyRecord{Actor: actor, Key: key}
+		var statusCode sql.NullInt64
+		var header []byte
+		err = conn.QueryRowContext(queryCtx,
+			"SELECT fingerprint, state, status_code, response_header, response_body, locked_at, expires_at "+
+				"FROM idempotency_keys WHERE actor = ? AND idem_key = ?", actor, key).
+			Scan(&existing.Fingerprint, &existing.State, &statusCode, &header, &existing.Body,
+				(*utcTime)(&existing.LockedAt), (*utcTime)(&existing.ExpiresAt))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			entry.Errorf("登録済みのIdempotency-Keyの取得に失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+		}
+		existing.StatusCode = int(statusCode.Int64)
+		if len(header) > 0 {
+			if err := json.Unmarshal(header, &existing.Header); err != nil {
+				entry.Errorf("保存したレスポンスヘッダーの読み込みに失敗しました: %v", err)
+				return nil, errors.DatabaseScanError()
+			}
+		}
+		return existing, nil
+	}
+	entry.Warnf("Idempotency-Keyを登録できませんでした: %s", key)
+	return nil, errors.IdempotencyKeyInProgressError()
+}
+
+// CompleteIdempotencyKey は処理中の Idempotency-Key にレスポンスを保存する
+func CompleteIdempotencyKey(ctx context.Context, db *sql.DB, actor, key string, statusCode int, header map[string][]string, body []byte) (err error) {
+	const query = "UPDATE idempotency_keys SET state = 'completed', status_code = ?, response_header = ?, response_body = ?, " +
+		"locked_at = NULL WHERE actor = ? AND idem_key = ? AND state = 'in_progress'"
+	ctx, span := startQuerySpan(ctx, "CompleteIdempotencyKey", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		entry.Errorf("レスポンスヘッダーの変換に失敗しました: %v", err)
+		return errors.UnexpectedError()
+	}
+
+	queryCtx, cancel := database.WithTimeout(ctx, "complete_idempotency_key")
+	defer cancel()
+
+	if _, err := database.Conn(ctx, db).ExecContext(queryCtx, query, statusCode, string(encodedHeader), body, actor, key); err != nil {
+		entry.Errorf("レスポンスの保存に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+	}
+	entry.Infof("Idempotency-Keyにレスポンスを保存しました: %s (status=%d)", key, statusCode)
+	return nil
+}
+
+// ReleaseIdempotencyKey は処理中の Idempotency-Key を削除し、同じキーで再試行できるようにする
+func ReleaseIdempotencyKey(ctx context.Context, db *sql.DB, actor, key string) (err error) {
+	const query = "DELETE FROM idempotency_keys WHERE actor = ? AND idem_key = ? AND state = 'in_progress'"
+	ctx, span := startQuerySpan(ctx, "ReleaseIdempotencyKey", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "release_idempotency_key")
+	defer cancel()
+
+	if _, err := database.Conn(ctx, db).ExecContext(queryCtx, query, actor, key); err != nil {
+		entry.Errorf("Idempotency-Keyの削除に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+	}
+	entry.Infof("Idempotency-Keyを削除しました: %s", key)
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys は有効期限を過ぎた Idempotency-Key を削除し、削除した件数を返す
+func PurgeExpiredIdempotencyKeys(ctx context.Context, db *sql.DB) (purged int64, err error) {
+	const query = "DELETE FROM idempotency_keys WHERE expires_at < ?"
+	ctx, span := startQuerySpan(ctx, "PurgeExpiredIdempotencyKeys", query)
+	defer func() {
+		span.SetAttribute("db.row_count", purged)
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "purge_expired_idempotency_keys")
+	defer cancel()
+
+	result, err := database.Conn(ctx, db).ExecContext(queryCtx, query, time.Now().UTC())
+	if err != nil {
+		entry.Errorf("期限切れのIdempotency-Keyの削除に失敗しました: %v", err)
+		return 0, contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+	}
+	purged, err = result.RowsAffected()
+	if err != nil {
+		entry.Errorf("削除した件数の取得に失敗しました: %v", err)
+		return 0, nil
+	}
+	return purged, nil
+}
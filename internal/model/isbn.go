@@ -0,0 +1,73 @@
+package model
+
+import (
+	"strings"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+)
+
+// NormalizeISBN は ISBN-10 または ISBN-13 を検証し、ISBN-13 に正規化して返す
+// ハイフンと空白は無視する
+func NormalizeISBN(isbn string) (string, *errors.UserDefinedError) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	switch len(digits) {
+	case 10:
+		if !isDigits(digits[:9]) || !(isDigits(digits[9:]) || digits[9] == 'X') {
+			return "", errors.ISBNFormatError()
+		}
+		if isbn10CheckDigit(digits[:9]) != digits[9] {
+			return "", errors.ISBNChecksumError()
+		}
+		// ISBN-10 は先頭に 978 を付け、チェックディジットを再計算して ISBN-13 に変換する
+		body := "978" + digits[:9]
+		return body + string(isbn13CheckDigit(body)), nil
+	case 13:
+		if !isDigits(digits) {
+			return "", errors.ISBNFormatError()
+		}
+		if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") {
+			return "", errors.ISBNFormatError()
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", errors.ISBNChecksumError()
+		}
+		return digits, nil
+	}
+	return "", errors.ISBNFormatError()
+}
+
+// ISBN-10 のチェックディジット (モジュラス11、重み10〜2) を計算する
+func isbn10CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// ISBN-13 のチェックディジット (モジュラス10、重み1・3) を計算する
+func isbn13CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(body[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	format := errors.ISBNFormatError().ErrorCode
+	checksum := errors.ISBNChecksumError().ErrorCode
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   string
+	}{
+		{"ISBN-13", "9784873119694", "9784873119694", ""},
+		{"ISBN-13 ハイフン区切り", "978-4-87311-969-4", "9784873119694", ""},
+		{"ISBN-13 空白区切り", " 978 4873 119694 ", "9784873119694", ""},
+		{"ISBN-13 979 で始まる", "979-10-323-0082-4", "9791032300824", ""},
+		{"ISBN-10 を ISBN-13 に変換する", "4-87311-969-3", "9784873119694", ""},
+		{"ISBN-10 チェックディジットが X", "0-8044-2957-X", "9780804429573", ""},
+		{"ISBN-10 チェックディジットが小文字の x", "080442957x", "9780804429573", ""},
+		{"ISBN-13 チェックディジットの誤り", "9784873119690", "", checksum},
+		{"ISBN-13 979 のチェックディジットの誤り", "9791032300825", "", checksum},
+		{"ISBN-10 チェックディジットの誤り", "4873119690", "", checksum},
+		{"ISBN-10 X であるべきチェックディジットの誤り", "0804429570", "", checksum},
+		{"ISBN-10 X ではないチェックディジットが X", "487311969X", "", checksum},
+		{"978・979 で始まらない13桁", "9771234567003", "", format},
+		{"ISBN-13 の末尾が X", "978487311969X", "", format},
+		{"ISBN-10 の途中に X", "48731X9693", "", format},
+		{"数字以外を含む", "978487311969A", "", format},
+		{"桁数が足りない", "487311969", "", format},
+		{"桁数が多い", "97848731196940", "", format},
+		{"空", "", "", format},
+		{"ハイフンのみ", "---", "", format},
+		{"全角数字", "９７８４８７３１１９６９４", "", format},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeISBN(tt.input)
+			if tt.err != "" {
+				if err == nil || err.ErrorCode != tt.err {
+					t.Errorf("NormalizeISBN(%q) = %q, %v, want エラー %s", tt.input, got, err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeISBN(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
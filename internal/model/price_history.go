@@ -0,0 +1,93 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// PriceHistory は本の価格の変更履歴
+type PriceHistory struct {
+	ID        string
+	BookID    string
+	OldPrice  *int // 登録時は nil
+	NewPrice  int
+	TrnID     string
+	ChangedAt time.Time
+}
+
+// recordPriceChange は価格の変更履歴を記録する
+// oldPrice が nil の場合は登録時の価格として記録する
+func recordPriceChange(ctx context.Context, db database.DBTX, bookID string, oldPrice *int, newPrice int) (err error) {
+	const query = "INSERT INTO price_history(book_id, old_price, new_price, trn_id) VALUES(?, ?, ?, ?)"
+	ctx, span := startQuerySpan(ctx, "RecordPriceChange", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "record_price_change")
+	defer cancel()
+
+	trnID, _ := ctx.Value(transaction.TrnIDKey).(string)
+	var old interface{}
+	if oldPrice != nil {
+		old = *oldPrice
+	}
+	if _, err := database.Conn(ctx, db).ExecContext(queryCtx, query, bookID, old, newPrice, trnID); err != nil {
+		entry.Errorf("価格の変更履歴の登録に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+	}
+	entry.Infof("価格の変更履歴を登録しました: book_id=%s, %v -> %d", bookID, old, newPrice)
+	return nil
+}
+
+// GetPriceHistory は本の価格の変更履歴を古い順に取得する
+func GetPriceHistory(ctx context.Context, db *sql.DB, bookID string) (history []PriceHistory, err error) {
+	const query = "SELECT id, book_id, old_price, new_price, trn_id, changed_at FROM price_history " +
+		"WHERE book_id = ? ORDER BY changed_at, id"
+	ctx, span := startQuerySpan(ctx, "GetPriceHistory", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(history))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_price_history")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, bookID)
+	if err != nil {
+		entry.Errorf("価格の変更履歴の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	history = []PriceHistory{}
+	for rows.Next() {
+		var h PriceHistory
+		var oldPrice sql.NullInt64
+		if err := rows.Scan(&h.ID, &h.BookID, &oldPrice, &h.NewPrice, &h.TrnID, (*utcTime)(&h.ChangedAt)); err != nil {
+			entry.Errorf("価格の変更履歴のスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		if oldPrice.Valid {
+			p := int(oldPrice.Int64)
+			h.OldPrice = &p
+		}
+		history = append(history, h)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("価格の変更履歴の読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	entry.Infof("価格の変更履歴の取得に成功しました (%d件)", len(history))
+	return history, nil
+}
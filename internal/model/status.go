@@ -0,0 +1,153 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// 本の購入ステータス
+const (
+	StatusWanted    = "wanted"    // 欲しい
+	StatusOrdered   = "ordered"   // 注文済み
+	StatusPurchased = "purchased" // 購入済み
+	StatusCancelled = "cancelled" // 購入中止
+)
+
+// すべての購入ステータス (表示順)
+var Statuses = []string{StatusWanted, StatusOrdered, StatusPurchased, StatusCancelled}
+
+// 変更元のステータスごとに、変更できるステータス
+var statusTransitions = map[string][]string{
+	StatusWanted:    {StatusOrdered, StatusPurchased, StatusCancelled},
+	StatusOrdered:   {StatusWanted, StatusPurchased, StatusCancelled},
+	StatusPurchased: {},
+	StatusCancelled: {StatusWanted},
+}
+
+type StatusInput struct {
+	Status *string `json:"status"`
+}
+
+// IsValidStatus は購入ステータスとして正しい値かどうかを判定する
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// CanTransition はステータスを from から to に変更できるかどうかを判定する
+func CanTransition(from, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionStatus は本の購入ステータスを変更し、変更者と変更日時を記録する
+// 許可されていない変更の場合は InvalidStatusTransitionError を返す
+// 変更は監査ログと同じトランザクションで行う
+func (b *Book) TransitionStatus(ctx context.Context, db *sql.DB, to, actor string) (err error) {
+	const query = "UPDATE books SET status = ?, status_changed_at = CURRENT_TIMESTAMP, status_changed_by = ?, " +
+		"updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ? AND status = ?"
+	ctx, span := startQuerySpan(ctx, "TransitionStatus", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	if !IsValidStatus(to) {
+		entry.Errorf("ステータスの値が不正です: %s", to)
+		return errors.InvalidStatusError()
+	}
+	if !CanTransition(b.Status, to) {
+		entry.Warnf("ステータスを'%s'から'%s'に変更することはできません", b.Status, to)
+		return errors.InvalidStatusTransitionError(b.Status, to)
+	}
+
+	queryCtx, cancel := database.WithTimeout(ctx, "transition_status")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		before, err := loadBookSnapshot(ctx, tx, b.ID)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.DeletedAt != nil {
+			entry.Warnf("ステータスを変更する本が見つかりません: id=%s", b.ID)
+			return errors.BookNotFoundError()
+		}
+
+		// 取得後に他のリクエストでステータスが変わっていた場合は更新しない
+		result, err := tx.ExecContext(queryCtx, query, to, actor, b.ID, b.Status)
+		if err != nil {
+			entry.Errorf("ステータスの更新に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			entry.Warnf("ステータスが他のリクエストで変更されました: id=%s", b.ID)
+			return errors.InvalidStatusTransitionError(b.Status, to)
+		}
+		return recordBookAudit(ctx, tx, AuditActionUpdate, b.ID, before)
+	})
+	if err != nil {
+		return err
+	}
+
+	entry.Infof("ステータスを変更しました: id=%s, %s -> %s, actor=%s", b.ID, b.Status, to, actor)
+	b.Status = to
+	b.Version++
+	return nil
+}
+
+// BookStats は購入ステータスごとの本の数
+type BookStats struct {
+	Total    int
+	ByStatus map[string]int
+}
+
+// GetBookStats は購入ステータスごとの本の数を取得する
+func GetBookStats(ctx context.Context, db *sql.DB) (stats *BookStats, err error) {
+	const query = "SELECT status, COUNT(*) FROM books WHERE deleted_at IS NULL GROUP BY status"
+	ctx, span := startQuerySpan(ctx, "GetBookStats", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_book_stats")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query)
+	if err != nil {
+		entry.Errorf("ステータスごとの本の数の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	stats = &BookStats{ByStatus: map[string]int{}}
+	for _, status := range Statuses {
+		stats.ByStatus[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			entry.Errorf("ステータスごとの本の数のスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		stats.ByStatus[status] = count
+		stats.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("ステータスごとの本の数の読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	return stats, nil
+}
@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+// すべてのステータスの組み合わせについて、変更できるかどうかを確認する
+func TestCanTransition(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{StatusWanted, StatusOrdered}:    true,
+		{StatusWanted, StatusPurchased}:  true,
+		{StatusWanted, StatusCancelled}:  true,
+		{StatusOrdered, StatusWanted}:    true,
+		{StatusOrdered, StatusPurchased}: true,
+		{StatusOrdered, StatusCancelled}: true,
+		{StatusCancelled, StatusWanted}:  true,
+	}
+	// 同じステータスへの変更と、purchased からの変更、cancelled から wanted 以外への変更はできない
+	for _, from := range Statuses {
+		for _, to := range Statuses {
+			want := allowed[[2]string{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	// 不正なステータスとの組み合わせは変更できない
+	for _, status := range Statuses {
+		for _, invalid := range []string{"", "unknown", "Wanted"} {
+			if CanTransition(status, invalid) || CanTransition(invalid, status) {
+				t.Errorf("CanTransition(%q, %q) または逆方向が true です", status, invalid)
+			}
+		}
+	}
+}
+
+func TestIsValidStatus(t *testing.T) {
+	tests := map[string]bool{
+		StatusWanted:    true,
+		StatusOrdered:   true,
+		StatusPurchased: true,
+		StatusCancelled: true,
+		"":              false,
+		"WANTED":        false,
+		" wanted":       false,
+		"canceled":      false,
+	}
+	for status, want := range tests {
+		if got := IsValidStatus(status); got != want {
+			t.Errorf("IsValidStatus(%q) = %v, want %v", status, got, want)
+		}
+	}
+	if len(Statuses) != 4 {
+		t.Errorf("Statuses = %v, want 4件", Statuses)
+	}
+}
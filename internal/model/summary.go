@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+// 価格の集計のグループ化の方法
+const (
+	GroupByNone   = ""
+	GroupByStatus = "status"
+	GroupByTag    = "tag"
+	GroupByMonth  = "month" // 登録月 (UTC)
+)
+
+// PriceSummary は価格の集計結果
+type PriceSummary struct {
+	Key     string
+	Count   int
+	Total   int
+	Average float64
+	Min     int
+	Max     int
+}
+
+// 集計に使用するカラム
+const summaryColumns = "COUNT(*), COALESCE(SUM(b.price), 0), COALESCE(AVG(b.price), 0), " +
+	"COALESCE(MIN(b.price), 0), COALESCE(MAX(b.price), 0)"
+
+// IsValidGroupBy は集計のグループ化の方法として正しい値かどうかを判定する
+func IsValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByNone, GroupByStatus, GroupByTag, GroupByMonth:
+		return true
+	}
+	return false
+}
+
+// GetPriceSummary は絞り込み条件に一致する本の価格を集計する
+// groupBy を指定した場合はグループごとの集計結果も返す
+func GetPriceSummary(ctx context.Context, db *sql.DB, filter BookFilter, groupBy string) (overall PriceSummary, groups []PriceSummary, err error) {
+	where, args := filter.where()
+	query := "SELECT " + summaryColumns + " FROM books b" + where
+	ctx, span := startQuerySpan(ctx, "GetPriceSummary", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(groups))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_price_summary")
+	defer cancel()
+
+	err = database.Conn(ctx, db).QueryRowContext(queryCtx, query, args...).
+		Scan(&overall.Count, &overall.Total, &overall.Average, &overall.Min, &overall.Max)
+	if err != nil {
+		entry.Errorf("価格の集計に失敗しました: %v", err)
+		return overall, nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+
+	groups = []PriceSummary{}
+	if groupBy == GroupByNone {
+		return overall, groups, nil
+	}
+
+	var groupQuery string
+	switch groupBy {
+	case GroupByStatus:
+		groupQuery = "SELECT b.status, " + summaryColumns + " FROM books b" + where +
+			" GROUP BY b.status ORDER BY b.status"
+	case GroupByTag:
+		// 複数のタグが付いた本はそれぞれのタグで集計される
+		groupQuery = "SELECT t.name, " + summaryColumns + " FROM books b " +
+			"JOIN book_tags bt ON bt.book_id = b.id JOIN tags t ON t.id = bt.tag_id" + where +
+			" GROUP BY t.name ORDER BY t.name"
+	case GroupByMonth:
+		groupQuery = "SELECT DATE_FORMAT(b.created_at, '%Y-%m') AS month, " + summaryColumns + " FROM books b" + where +
+			" GROUP BY month ORDER BY month"
+	default:
+		return overall, nil, errors.InvalidQueryParamError("group_by")
+	}
+	span.SetAttribute("db.statement", groupQuery)
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, groupQuery, args...)
+	if err != nil {
+		entry.Errorf("グループごとの価格の集計に失敗しました: %v", err)
+		return overall, nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s PriceSummary
+		if err := rows.Scan(&s.Key, &s.Count, &s.Total, &s.Average, &s.Min, &s.Max); err != nil {
+			entry.Errorf("価格の集計結果のスキャンに失敗しました: %v", err)
+			return overall, nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		groups = append(groups, s)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("価格の集計結果の読み込みに失敗しました: %v", err)
+		return overall, nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	entry.Infof("価格の集計に成功しました (group_by=%s, %d件)", groupBy, len(groups))
+	return overall, groups, nil
+}
@@ -0,0 +1,361 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/HwaI12/go-api-tutorial/internal/validation"
+	"github.com/HwaI12/go-api-tutorial/pkg/database"
+)
+
+type Tag struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	BookCount int       `json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TagInput struct {
+	Name *string `json:"name"`
+}
+
+type TagMergeInput struct {
+	Into *string `json:"into"`
+}
+
+// NormalizeTagName はタグの名前を正規化して検証する
+func NormalizeTagName(ctx context.Context, name string) (string, error) {
+	entry := logger.WithTransaction(ctx)
+	rules := validation.Current().Tag
+
+	name = rules.Name.Normalize(name)
+	length := validation.Length(name)
+
+	if name == "" {
+		entry.Errorf("タグの名前が空です")
+		return "", errors.TagNameEmptyError()
+	}
+	if rules.Name.MaxLength > 0 && length > rules.Name.MaxLength {
+		entry.Errorf("タグの名前が長すぎます。%d文字以内で書いてください (%d文字)", rules.Name.MaxLength, length)
+		return "", errors.TagNameTooLongError(rules.Name.MaxLength)
+	}
+	if length < rules.Name.MinLength {
+		entry.Errorf("タグの名前が短すぎます。%d文字以上で書いてください (%d文字)", rules.Name.MinLength, length)
+		return "", errors.TagNameTooShortError(rules.Name.MinLength)
+	}
+	if !rules.MatchPattern(name) {
+		entry.Errorf("タグの名前に使用できない文字が含まれています: %q", name)
+		return "", errors.TagNameInvalidError()
+	}
+	return name, nil
+}
+
+// normalizeTagNames は本に付けるタグの名前をすべて正規化して検証する
+// 重複したタグは1つにまとめる
+func normalizeTagNames(ctx context.Context, names []string) ([]string, error) {
+	rules := validation.Current().Tag
+	normalized := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		n, err := NormalizeTagName(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		// データベースの照合順序に合わせ、大文字・小文字の違いは同じタグとみなす
+		key := strings.ToLower(n)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, n)
+	}
+	if rules.MaxPerBook > 0 && len(normalized) > rules.MaxPerBook {
+		logger.WithTransaction(ctx).Errorf("タグが多すぎます。%d個以内で指定してください (%d個)", rules.MaxPerBook, len(normalized))
+		return nil, errors.TooManyTagsError(rules.MaxPerBook)
+	}
+	return normalized, nil
+}
+
+// GetTags はタグの一覧を、タグが付けられた本の数とともに取得する
+func GetTags(ctx context.Context, db *sql.DB) (tags []Tag, err error) {
+	// 論理削除した本は数に含めない
+	const query = "SELECT t.id, t.name, t.created_at, COUNT(bt.book_id) FROM tags t " +
+		"LEFT JOIN (book_tags bt JOIN books b ON b.id = bt.book_id AND b.deleted_at IS NULL) ON bt.tag_id = t.id " +
+		"GROUP BY t.id, t.name, t.created_at ORDER BY t.name"
+	ctx, span := startQuerySpan(ctx, "GetTags", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(tags))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_tags")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query)
+	if err != nil {
+		entry.Errorf("タグの取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	tags = []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, (*utcTime)(&tag.CreatedAt), &tag.BookCount); err != nil {
+			entry.Errorf("タグのスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("タグの読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	entry.Infof("タグの取得に成功しました (%d件)", len(tags))
+	return tags, nil
+}
+
+// GetTag は指定したIDのタグを取得する
+func GetTag(ctx context.Context, db *sql.DB, id string) (tag *Tag, err error) {
+	const query = "SELECT t.id, t.name, t.created_at, (SELECT COUNT(*) FROM book_tags bt " +
+		"JOIN books b ON b.id = bt.book_id AND b.deleted_at IS NULL WHERE bt.tag_id = t.id) " +
+		"FROM tags t WHERE t.id = ?"
+	ctx, span := startQuerySpan(ctx, "GetTag", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_tag")
+	defer cancel()
+
+	tag = &Tag{}
+	err = database.Conn(ctx, db).QueryRowContext(queryCtx, query, id).Scan(&tag.ID, &tag.Name, (*utcTime)(&tag.CreatedAt), &tag.BookCount)
+	if err == sql.ErrNoRows {
+		entry.Warnf("タグが見つかりません: id=%s", id)
+		return nil, errors.TagNotFoundError()
+	}
+	if err != nil {
+		entry.Errorf("タグの取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+	return tag, nil
+}
+
+// RenameTag はタグの名前を変更する
+// 変更後の名前のタグが既に存在する場合は TagNameConflictError を返す
+func (t *Tag) RenameTag(ctx context.Context, db *sql.DB) (err error) {
+	const query = "UPDATE tags SET name = ? WHERE id = ?"
+	ctx, span := startQuerySpan(ctx, "RenameTag", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "rename_tag")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		before, err := loadTagSnapshot(ctx, tx, t.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(queryCtx, query, t.Name, t.ID); err != nil {
+			if database.IsDuplicateKeyError(err) {
+				entry.Warnf("同じ名前のタグが既に存在します: %s", t.Name)
+				return errors.TagNameConflictError()
+			}
+			entry.Errorf("タグの名前の変更に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseUpdateError())
+		}
+		return recordAudit(ctx, tx, AuditActionUpdate, AuditEntityTag, t.ID, before, tagSnapshot{Name: t.Name})
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("タグの名前を変更しました: id=%s, name=%s", t.ID, t.Name)
+	return nil
+}
+
+// MergeTags は統合元のタグが付いた本に統合先のタグを付け、統合元のタグを削除する
+func MergeTags(ctx context.Context, db *sql.DB, sourceID, targetID string) (err error) {
+	// INSERT IGNORE は外部キー制約の違反も警告にしてしまうため、統合先に既に付いている本を除いて付け替える
+	const query = "INSERT INTO book_tags(book_id, tag_id) SELECT bt.book_id, ? FROM book_tags bt WHERE bt.tag_id = ? " +
+		"AND NOT EXISTS (SELECT 1 FROM book_tags t WHERE t.book_id = bt.book_id AND t.tag_id = ?)"
+	ctx, span := startQuerySpan(ctx, "MergeTags", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	source, sourceErr := strconv.ParseInt(sourceID, 10, 64)
+	target, targetErr := strconv.ParseInt(targetID, 10, 64)
+	if sourceErr != nil || targetErr != nil {
+		entry.Warnf("タグのIDが不正です: %s -> %s", sourceID, targetID)
+		return errors.TagNotFoundError()
+	}
+	if source == target {
+		entry.Warnf("統合元と統合先に同じタグが指定されています: %d", source)
+		return errors.TagMergeSameError()
+	}
+
+	queryCtx, cancel := database.WithTimeout(ctx, "merge_tags")
+	defer cancel()
+
+	err = inTx(ctx, db, func(tx database.DBTX) error {
+		// デッドロックを避けるため、IDの小さい順にタグの行をロックする
+		// 統合先もロックし、統合中に他のリクエストで削除されないようにする
+		var before *tagSnapshot
+		for _, id := range sortedTagIDs(source, target) {
+			snapshot, err := loadTagSnapshot(ctx, tx, strconv.FormatInt(id, 10))
+			if err != nil {
+				return err
+			}
+			if id == source {
+				before = snapshot
+			}
+		}
+		if _, err := tx.ExecContext(queryCtx, query, target, source, target); err != nil {
+			entry.Errorf("タグの付け替えに失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+		if _, err := tx.ExecContext(queryCtx, "DELETE FROM tags WHERE id = ?", source); err != nil {
+			entry.Errorf("統合元のタグの削除に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+		}
+		// 統合元のタグの監査ログに統合先を記録する
+		mergedInto := strconv.FormatInt(target, 10)
+		return recordAudit(ctx, tx, AuditActionMerge, AuditEntityTag, strconv.FormatInt(source, 10), before, tagSnapshot{MergedInto: &mergedInto})
+	})
+	if err != nil {
+		return err
+	}
+	entry.Infof("タグを統合しました: %d -> %d", source, target)
+	return nil
+}
+
+// 2つのタグのIDを小さい順に並べる
+func sortedTagIDs(a, b int64) []int64 {
+	if a < b {
+		return []int64{a, b}
+	}
+	return []int64{b, a}
+}
+
+// tagSnapshot は監査ログに記録するタグの状態
+type tagSnapshot struct {
+	Name       string  `json:"name,omitempty"`
+	MergedInto *string `json:"merged_into,omitempty"` // 統合先のタグのID
+}
+
+// loadTagSnapshot はトランザクション内でタグの現在の状態を取得する
+// 変更が終わるまで他のトランザクションから変更されないよう、タグの行をロックする
+// タグが存在しない場合は TagNotFoundError を返す
+func loadTagSnapshot(ctx context.Context, tx database.DBTX, id string) (*tagSnapshot, error) {
+	const query = "SELECT name FROM tags WHERE id = ? FOR UPDATE"
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "load_tag_snapshot")
+	defer cancel()
+
+	snapshot := &tagSnapshot{}
+	err := tx.QueryRowContext(queryCtx, query, id).Scan(&snapshot.Name)
+	if err == sql.ErrNoRows {
+		entry.Warnf("タグが見つかりません: id=%s", id)
+		return nil, errors.TagNotFoundError()
+	}
+	if err != nil {
+		entry.Errorf("タグの状態の取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseSelectError())
+	}
+	return snapshot, nil
+}
+
+// getTagsByBookIDs は本ごとのタグの名前の一覧を取得する
+func getTagsByBookIDs(ctx context.Context, db *sql.DB, bookIDs []string) (tags map[string][]string, err error) {
+	tags = map[string][]string{}
+	if len(bookIDs) == 0 {
+		return tags, nil
+	}
+
+	query := "SELECT bt.book_id, t.name FROM book_tags bt JOIN tags t ON t.id = bt.tag_id " +
+		"WHERE bt.book_id IN (" + placeholders(len(bookIDs)) + ") ORDER BY bt.book_id, t.name"
+	ctx, span := startQuerySpan(ctx, "GetTagsByBookIDs", query)
+	defer func() {
+		span.SetAttribute("db.row_count", len(tags))
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "get_tags_by_book_ids")
+	defer cancel()
+
+	rows, err := database.Conn(ctx, db).QueryContext(queryCtx, query, stringArgs(bookIDs)...)
+	if err != nil {
+		entry.Errorf("本のタグの取得に失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseQueryError())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID, name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			entry.Errorf("本のタグのスキャンに失敗しました: %v", err)
+			return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+		}
+		tags[bookID] = append(tags[bookID], name)
+	}
+	if err := rows.Err(); err != nil {
+		entry.Errorf("本のタグの読み込みに失敗しました: %v", err)
+		return nil, contextError(ctx, queryCtx, errors.DatabaseScanError())
+	}
+	return tags, nil
+}
+
+// setBookTags は本に付けるタグを置き換える
+// 存在しないタグは新しく作成する
+func setBookTags(ctx context.Context, db database.DBTX, bookID string, names []string) (err error) {
+	const query = "INSERT INTO book_tags(book_id, tag_id) SELECT ?, id FROM tags WHERE name = ?"
+	ctx, span := startQuerySpan(ctx, "SetBookTags", query)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	entry := logger.WithTransaction(ctx)
+
+	queryCtx, cancel := database.WithTimeout(ctx, "set_book_tags")
+	defer cancel()
+
+	if _, err := database.Conn(ctx, db).ExecContext(queryCtx, "DELETE FROM book_tags WHERE book_id = ?", bookID); err != nil {
+		entry.Errorf("本とタグの関連の削除に失敗しました: %v", err)
+		return contextError(ctx, queryCtx, errors.DatabaseDeleteError())
+	}
+	for _, name := range names {
+		if _, err := database.Conn(ctx, db).ExecContext(queryCtx, "INSERT IGNORE INTO tags(name) VALUES(?)", name); err != nil {
+			entry.Errorf("タグの登録に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+		if _, err := database.Conn(ctx, db).ExecContext(queryCtx, query, bookID, name); err != nil {
+			if database.IsDuplicateKeyError(err) {
+				// 大文字・小文字以外にも、データベースの照合順序で同じとみなされる名前 (例: アクセントの有無) が含まれている
+				entry.Errorf("同じタグとみなされる名前が重複して指定されています: %q", name)
+				return errors.TagNameDuplicateError()
+			}
+			entry.Errorf("本とタグの関連の登録に失敗しました: %v", err)
+			return contextError(ctx, queryCtx, errors.DatabaseInsertError())
+		}
+	}
+	entry.Infof("本とタグの関連を更新しました: book_id=%s, tags=%v", bookID, names)
+	return nil
+}
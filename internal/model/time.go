@@ -0,0 +1,38 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// データベースの日時の文字列表現 (parseTime が無効な場合に使用)
+const dbTimeLayout = "2006-01-02 15:04:05.999999"
+
+// utcTime はデータベースの日時を UTC の time.Time として読み込むためのスキャナー
+// parseTime の有効・無効どちらの接続でも同じ結果になるようにする
+type utcTime time.Time
+
+func (t *utcTime) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t = utcTime(v.UTC())
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case nil:
+		*t = utcTime(time.Time{})
+		return nil
+	}
+	return fmt.Errorf("日時に変換できない型です: %T", src)
+}
+
+func (t *utcTime) parse(s string) error {
+	parsed, err := time.ParseInLocation(dbTimeLayout, s, time.UTC)
+	if err != nil {
+		return err
+	}
+	*t = utcTime(parsed)
+	return nil
+}
@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const serviceName = "go-api-tutorial"
+
+// Exporter は終了したスパンを出力する
+type Exporter interface {
+	ExportSpan(span *Span) error
+}
+
+var (
+	exporterMu sync.RWMutex
+	exporter   Exporter
+)
+
+// トレースの初期設定を行う
+// 環境変数 TRACE_EXPORTER に stdout または file を指定するとスパンを出力する
+// file の場合は TRACE_FILE (デフォルト: logs/traces.jsonl) に追記する
+// 返り値の関数はエクスポーターを解除して出力先のファイルを閉じる。終了時に呼び出すこと
+func InitializeTracer() (func() error, error) {
+	switch os.Getenv("TRACE_EXPORTER") {
+	case "stdout":
+		SetExporter(NewJSONExporter(os.Stdout))
+	case "file":
+		path := os.Getenv("TRACE_FILE")
+		if path == "" {
+			path = "logs/traces.jsonl"
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			SetExporter(nil)
+			return noopClose, fmt.Errorf("トレース出力先ディレクトリの作成に失敗しました: %v", err)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			SetExporter(nil)
+			return noopClose, fmt.Errorf("トレース出力先ファイルのオープンに失敗しました: %v", err)
+		}
+		e := NewJSONExporter(file)
+		SetExporter(e)
+		return func() error {
+			SetExporter(nil)
+			// 書き込み中のスパンがあれば終わるのを待ってから閉じる
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			return file.Close()
+		}, nil
+	default:
+		SetExporter(nil)
+	}
+	return noopClose, nil
+}
+
+// 閉じる必要のある出力先がない場合に返す関数
+func noopClose() error { return nil }
+
+// エクスポーターを設定する。nil を指定するとスパンを出力しない
+func SetExporter(e Exporter) {
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+	exporter = e
+}
+
+func export(span *Span) {
+	exporterMu.RLock()
+	e := exporter
+	exporterMu.RUnlock()
+	if e == nil {
+		return
+	}
+	if err := e.ExportSpan(span); err != nil {
+		logrus.WithError(err).Error("スパンの出力に失敗しました")
+	}
+}
+
+// JSONExporter はスパンを OTLP 互換の JSON として1行ずつ書き出す
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// 新しい JSONExporter を作成して返す
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+func (e *JSONExporter) ExportSpan(span *Span) error {
+	b, err := json.Marshal(toOTLP(span))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+// 以下は OTLP/JSON (ExportTraceServiceRequest) の構造
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func toOTLP(span *Span) otlpRequest {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	attributes := make([]otlpKeyValue, 0, len(span.Attributes))
+	for _, a := range span.Attributes {
+		attributes = append(attributes, otlpKeyValue{Key: a.Key, Value: otlpValue(a.Value)})
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []otlpKeyValue{
+				{Key: "service.name", Value: otlpValue(serviceName)},
+			}},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/HwaI12/go-api-tutorial/internal/tracing"},
+				Spans: []otlpSpan{{
+					TraceID:           span.TraceID,
+					SpanID:            span.SpanID,
+					ParentSpanID:      span.ParentSpanID,
+					Name:              span.Name,
+					Kind:              span.Kind,
+					StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+					EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+					Attributes:        attributes,
+					Status:            otlpStatus{Code: span.StatusCode, Message: span.StatusMessage},
+				}},
+			}},
+		}},
+	}
+}
+
+// 属性値を OTLP の AnyValue 形式に変換する
+func otlpValue(v interface{}) map[string]interface{} {
+	switch val := v.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": val}
+	case bool:
+		return map[string]interface{}{"boolValue": val}
+	case int:
+		return map[string]interface{}{"intValue": strconv.Itoa(val)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": val}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprint(val)}
+	}
+}
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+)
+
+// スパンを OTLP/JSON の1行として書き出す
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	SetExporter(NewJSONExporter(&buf))
+	t.Cleanup(func() { SetExporter(nil) })
+
+	ctx := context.WithValue(context.Background(), transaction.TrnIDKey, "0123ABCD-0000-0000-0000-00000000FFFF")
+	ctx, root := StartSpan(ctx, "GET /books", KindServer)
+	_, child := StartSpan(ctx, "SELECT books", KindClient)
+	child.SetAttribute("db.rows", 3)
+	child.SetAttribute("db.rows", int64(4))
+	child.SetAttribute("cache.hit", false)
+	child.SetAttribute("ratio", 0.5)
+	child.SetAttribute("other", []string{"a"})
+	child.SetStatus(StatusError, "タイムアウト")
+	child.End()
+	child.End()
+	root.End()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("出力行数 = %d, want 2\n%s", len(lines), buf.String())
+	}
+	var got otlpRequest
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("JSON を解析できません: %v", err)
+	}
+	resource := got.ResourceSpans[0]
+	if kv := resource.Resource.Attributes[0]; kv.Key != "service.name" || kv.Value["stringValue"] != serviceName {
+		t.Errorf("resource = %+v", resource.Resource)
+	}
+	span := resource.ScopeSpans[0].Spans[0]
+	if span.TraceID != "0123abcd00000000000000000000ffff" || span.ParentSpanID != root.SpanID || span.SpanID != child.SpanID {
+		t.Errorf("traceId=%s parentSpanId=%s spanId=%s", span.TraceID, span.ParentSpanID, span.SpanID)
+	}
+	if span.Name != "SELECT books" || span.Kind != KindClient || span.Status != (otlpStatus{Code: StatusError, Message: "タイムアウト"}) {
+		t.Errorf("span = %+v", span)
+	}
+	if span.StartTimeUnixNano == "" || span.EndTimeUnixNano < span.StartTimeUnixNano {
+		t.Errorf("start=%s end=%s", span.StartTimeUnixNano, span.EndTimeUnixNano)
+	}
+	want := map[string]map[string]interface{}{
+		"db.rows":   {"intValue": "4"},
+		"cache.hit": {"boolValue": false},
+		"ratio":     {"doubleValue": 0.5},
+		"other":     {"stringValue": "[a]"},
+	}
+	if len(span.Attributes) != len(want) {
+		t.Errorf("attributes = %+v", span.Attributes)
+	}
+	for _, kv := range span.Attributes {
+		for k, v := range want[kv.Key] {
+			if kv.Value[k] != v {
+				t.Errorf("attributes[%s] = %v, want %s=%v", kv.Key, kv.Value, k, v)
+			}
+		}
+	}
+
+	var rootGot otlpRequest
+	if err := json.Unmarshal([]byte(lines[1]), &rootGot); err != nil {
+		t.Fatal(err)
+	}
+	span = rootGot.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.ParentSpanID != "" || span.Attributes[0].Key != "trn_id" {
+		t.Errorf("ルートスパン = %+v", span)
+	}
+}
+
+// TRACE_EXPORTER=file の場合は TRACE_FILE に追記し、返り値の関数でファイルを閉じる
+func TestInitializeTracerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "traces.jsonl")
+	t.Setenv("TRACE_EXPORTER", "file")
+	t.Setenv("TRACE_FILE", path)
+
+	for i := 0; i < 2; i++ {
+		closeTracer, err := InitializeTracer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, span := StartSpan(context.Background(), "job", KindInternal)
+		span.End()
+		if err := closeTracer(); err != nil {
+			t.Fatalf("クローズに失敗しました: %v", err)
+		}
+	}
+	// 閉じた後のスパンは出力しない
+	_, span := StartSpan(context.Background(), "after close", KindInternal)
+	span.End()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 || strings.Contains(string(data), "after close") {
+		t.Errorf("出力 = %s, want 2行", data)
+	}
+}
+
+// TRACE_EXPORTER を指定しない場合はスパンを出力しない
+func TestInitializeTracerDisabled(t *testing.T) {
+	SetExporter(NewJSONExporter(&bytes.Buffer{}))
+	t.Setenv("TRACE_EXPORTER", "")
+	closeTracer, err := InitializeTracer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeTracer()
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	if exporter != nil {
+		t.Errorf("エクスポーター = %T, want nil", exporter)
+	}
+}
+
+// 出力先を作成できない場合はエラーを返し、スパンを出力しない
+func TestInitializeTracerFileError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TRACE_EXPORTER", "file")
+	t.Setenv("TRACE_FILE", filepath.Join(file, "traces.jsonl"))
+	closeTracer, err := InitializeTracer()
+	if err == nil {
+		t.Error("エラーになりませんでした")
+	}
+	if err := closeTracer(); err != nil {
+		t.Errorf("クローズに失敗しました: %v", err)
+	}
+}
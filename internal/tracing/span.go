@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/transaction"
+)
+
+type ctxKey string
+
+const spanKey ctxKey = "span"
+
+// OTLP の SpanKind に対応する値
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// OTLP の StatusCode に対応する値
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// Attribute はスパンに付与するキーと値の組
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span は処理の開始から終了までの区間を表す
+type Span struct {
+	mu            sync.Mutex
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	Name          string
+	Kind          int
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Attribute
+	StatusCode    int
+	StatusMessage string
+	ended         bool
+}
+
+// 新しいスパンを開始し、スパンを含むコンテキストを返す
+// コンテキストに親スパンがない場合はルートスパンとなり、トレースIDはトランザクションIDから生成する
+func StartSpan(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	span := &Span{
+		SpanID:    newID(8),
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		trnID, _ := ctx.Value(transaction.TrnIDKey).(string)
+		span.TraceID = traceIDFromTrnID(trnID)
+		span.SetAttribute("trn_id", trnID)
+	}
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// コンテキストから現在のスパンを取得する
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// スパンに属性を設定する。同じキーがあれば上書きする
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.Attributes {
+		if s.Attributes[i].Key == key {
+			s.Attributes[i].Value = value
+			return
+		}
+	}
+	s.Attributes = append(s.Attributes, Attribute{Key: key, Value: value})
+}
+
+// スパンにエラーを記録する
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// スパンのステータスを設定する
+func (s *Span) SetStatus(code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.StatusCode = code
+	s.StatusMessage = message
+}
+
+// スパンを終了し、エクスポーターに渡す
+// 2回目以降の呼び出しは無視する
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+
+	export(s)
+}
+
+// トランザクションID (UUID) をハイフンを除いた16バイトのトレースIDに変換する
+// UUID として解釈できない場合はランダムなIDを生成する
+func traceIDFromTrnID(trnID string) string {
+	id := strings.ReplaceAll(trnID, "-", "")
+	if len(id) == 32 {
+		if _, err := hex.DecodeString(id); err == nil {
+			return strings.ToLower(id)
+		}
+	}
+	return newID(16)
+}
+
+func newID(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
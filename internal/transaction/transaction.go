@@ -12,6 +12,7 @@ type ctxKey string
 const (
 	TrnIDKey   ctxKey = "trn_id"
 	TrnTimeKey ctxKey = "trn_time"
+	RouteKey   ctxKey = "route"
 )
 
 type TransactionInfo struct {
@@ -46,3 +47,21 @@ func InitializeTransaction(ctx context.Context) context.Context {
 	ctx = context.WithValue(ctx, TrnTimeKey, trnTime)
 	return ctx
 }
+
+// リクエストごとに新しいトランザクション情報を生成し、コンテキストに設定する。
+func NewTransaction(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, TrnIDKey, uuid.New().String())
+	ctx = context.WithValue(ctx, TrnTimeKey, time.Now().Format(time.RFC3339))
+	return ctx
+}
+
+// リクエストのルート (メソッドとルートテンプレート) をコンテキストに設定する
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, RouteKey, route)
+}
+
+// コンテキストからリクエストのルートを取得する。設定されていない場合は空文字を返す
+func RouteFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(RouteKey).(string)
+	return route
+}
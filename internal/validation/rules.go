@@ -0,0 +1,208 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TextRule は文字列パラメータの検証ルール
+// 文字数はUnicode正規化後のコードポイント数で数える (MySQL の VARCHAR と同じ数え方)
+type TextRule struct {
+	MinLength     int    `json:"min_length"`
+	MaxLength     int    `json:"max_length"`
+	TrimSpace     bool   `json:"trim_space"`
+	Normalization string `json:"normalization"` // NFC / NFKC / none
+}
+
+// PriceRule は価格パラメータの検証ルール
+type PriceRule struct {
+	AllowZero bool `json:"allow_zero"`
+	Min       int  `json:"min"`
+	Max       int  `json:"max"`
+}
+
+// BookRules は本の検証ルール
+type BookRules struct {
+	Name  TextRule  `json:"name"`
+	Price PriceRule `json:"price"`
+}
+
+// AuthorRules は著者の検証ルール
+type AuthorRules struct {
+	Name TextRule `json:"name"`
+}
+
+// TagRules はタグの検証ルール
+type TagRules struct {
+	Name       TextRule `json:"name"`
+	Pattern    string   `json:"pattern"`      // タグ名が一致すべき正規表現
+	MaxPerBook int      `json:"max_per_book"` // 1冊の本に付けられるタグの最大数
+}
+
+// Rules は検証ルール全体
+type Rules struct {
+	Book   BookRules   `json:"book"`
+	Author AuthorRules `json:"author"`
+	Tag    TagRules    `json:"tag"`
+}
+
+// 設定ファイルがない場合に使用するデフォルトのルール
+func DefaultRules() Rules {
+	return Rules{
+		Book: BookRules{
+			Name: TextRule{
+				MinLength:     1,
+				MaxLength:     50,
+				TrimSpace:     true,
+				Normalization: "NFC",
+			},
+			Price: PriceRule{
+				AllowZero: false,
+				Min:       1,
+				Max:       20000,
+			},
+		},
+		Author: AuthorRules{
+			Name: TextRule{
+				MinLength:     1,
+				MaxLength:     100,
+				TrimSpace:     true,
+				Normalization: "NFC",
+			},
+		},
+		Tag: TagRules{
+			Name: TextRule{
+				MinLength:     1,
+				MaxLength:     30,
+				TrimSpace:     true,
+				Normalization: "NFKC",
+			},
+			// 一覧の絞り込みでカンマ区切りを使うため、カンマと制御文字は使用できない
+			Pattern:    `^[^,\p{Cc}]+$`,
+			MaxPerBook: 10,
+		},
+	}
+}
+
+var (
+	rulesMu sync.RWMutex
+	current = DefaultRules()
+)
+
+// 検証ルールの初期設定を行う
+// 環境変数 VALIDATION_RULES_FILE が設定されていれば、そのJSONファイルからルールを読み込む
+func InitializeRules() error {
+	path := config.GetString("VALIDATION_RULES_FILE", "")
+	if path == "" {
+		SetRules(DefaultRules())
+		return nil
+	}
+	rules, err := LoadRules(path)
+	if err != nil {
+		return err
+	}
+	SetRules(rules)
+	return nil
+}
+
+// JSONファイルから検証ルールを読み込む
+// ファイルに記載のない項目はデフォルトのルールを使用する
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("検証ルールファイルの読み込みに失敗しました: %v", err)
+	}
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return rules, fmt.Errorf("検証ルールファイルの解析に失敗しました: %v", err)
+	}
+	if err := rules.validate(); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+// 現在の検証ルールを設定する
+func SetRules(rules Rules) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	current = rules
+}
+
+// 現在の検証ルールを返す
+func Current() Rules {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return current
+}
+
+// ルール自体が矛盾していないかを確認する
+func (r Rules) validate() error {
+	if err := r.Book.Name.validate("book.name"); err != nil {
+		return err
+	}
+	if err := r.Author.Name.validate("author.name"); err != nil {
+		return err
+	}
+	if err := r.Tag.Name.validate("tag.name"); err != nil {
+		return err
+	}
+	if _, err := regexp.Compile(r.Tag.Pattern); err != nil {
+		return fmt.Errorf("検証ルール'tag.pattern'の正規表現が不正です: %v", err)
+	}
+	if r.Book.Price.Max > 0 && r.Book.Price.Min > r.Book.Price.Max {
+		return fmt.Errorf("検証ルール'book.price'のminがmaxより大きくなっています")
+	}
+	return nil
+}
+
+func (r TextRule) validate(name string) error {
+	switch strings.ToUpper(r.Normalization) {
+	case "", "NONE", "NFC", "NFKC":
+	default:
+		return fmt.Errorf("検証ルール'%s'のnormalizationに未対応の値'%s'が指定されています", name, r.Normalization)
+	}
+	if r.MaxLength > 0 && r.MinLength > r.MaxLength {
+		return fmt.Errorf("検証ルール'%s'のmin_lengthがmax_lengthより大きくなっています", name)
+	}
+	return nil
+}
+
+// ルールに従って文字列を正規化する (前後の空白の除去とUnicode正規化)
+func (r TextRule) Normalize(s string) string {
+	switch strings.ToUpper(r.Normalization) {
+	case "NFC":
+		s = norm.NFC.String(s)
+	case "NFKC":
+		s = norm.NFKC.String(s)
+	}
+	if r.TrimSpace {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// 文字列の文字数を返す
+func Length(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// タグ名がパターンに一致するかを判定する。パターンが空の場合は常に一致する
+func (r TagRules) MatchPattern(name string) bool {
+	if r.Pattern == "" {
+		return true
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(name)
+}
@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	nfc := TextRule{TrimSpace: true, Normalization: "NFC"}
+	nfkc := TextRule{TrimSpace: true, Normalization: "NFKC"}
+	none := TextRule{Normalization: "none"}
+	tests := []struct {
+		name   string
+		rule   TextRule
+		input  string
+		want   string
+		length int
+	}{
+		{"NFC 濁点の結合文字を合成する", nfc, "か\u3099", "が", 1},
+		{"NFC 半濁点の結合文字を合成する", nfc, "ハ\u309a", "パ", 1},
+		{"NFC 合成済みの文字はそのまま", nfc, "がぎぐげご", "がぎぐげご", 5},
+		{"NFC 半角カナは変換しない", nfc, "ｶﾞｲﾄﾞ", "ｶﾞｲﾄﾞ", 5},
+		{"NFC 全角英数字は変換しない", nfc, "Ｇｏ言語", "Ｇｏ言語", 4},
+		{"NFC アクセント記号を合成する", nfc, "Cafe\u0301", "Caf\u00e9", 4},
+		{"NFC 合成できない結合文字は別の文字として数える", nfc, "q\u0307", "q\u0307", 2},
+		{"NFC 複数の結合文字を正規の順序に並べて合成する", nfc, "a\u0302\u0323", "\u1ead", 1},
+		{"NFC 前後の空白 (全角スペースを含む) を除去する", nfc, "　 Go入門 \t", "Go入門", 4},
+		{"NFC 異体字セレクタは別の文字として数える", nfc, "葛\U000E0100", "葛\U000E0100", 2},
+		{"NFKC 半角カナを全角にする", nfkc, "ｶﾞｲﾄﾞ", "ガイド", 3},
+		{"NFKC 全角英数字を半角にする", nfkc, "Ｇｏ１２３", "Go123", 5},
+		{"NFKC 丸数字を数字にする", nfkc, "①", "1", 1},
+		{"NFKC 単位記号を展開する", nfkc, "㌔", "キロ", 2},
+		{"NFKC 全角スペースを除去する", nfkc, "　タグ　", "タグ", 2},
+		{"none 正規化しない", none, " か\u3099 ", " か\u3099 ", 4},
+		{"normalization の大文字小文字を区別しない", TextRule{Normalization: "nfc"}, "か\u3099", "が", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Normalize(tt.input)
+			if got != tt.want {
+				t.Errorf("Normalize(%+q) = %+q, want %+q", tt.input, got, tt.want)
+			}
+			if n := Length(got); n != tt.length {
+				t.Errorf("Length(%+q) = %d, want %d", got, n, tt.length)
+			}
+		})
+	}
+}
+
+// 文字数の上限は正規化後の文字数で判定する
+func TestNameLengthLimit(t *testing.T) {
+	rules := DefaultRules()
+	tests := []struct {
+		name  string
+		rule  TextRule
+		input string
+		ok    bool
+	}{
+		{"本の名前 上限ちょうど", rules.Book.Name, strings.Repeat("本", 50), true},
+		{"本の名前 上限超過", rules.Book.Name, strings.Repeat("本", 51), false},
+		{"本の名前 結合文字は合成後の文字数で数える", rules.Book.Name, strings.Repeat("か\u3099", 50), true},
+		{"本の名前 合成できない結合文字はそれぞれ1文字として数える", rules.Book.Name, strings.Repeat("q\u0307", 25) + "本", false},
+		{"本の名前 前後の空白は数えない", rules.Book.Name, "  " + strings.Repeat("本", 50) + "　", true},
+		{"本の名前 空白のみは空として扱う", rules.Book.Name, "　 ", false},
+		{"著者名 上限ちょうど", rules.Author.Name, strings.Repeat("著", 100), true},
+		{"著者名 上限超過", rules.Author.Name, strings.Repeat("著", 101), false},
+		{"タグ名 半角カナは全角にしてから数える", rules.Tag.Name, strings.Repeat("ｶﾞ", 30), true},
+		{"タグ名 上限超過", rules.Tag.Name, strings.Repeat("タ", 31), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := Length(tt.rule.Normalize(tt.input))
+			if ok := n >= tt.rule.MinLength && n <= tt.rule.MaxLength; ok != tt.ok {
+				t.Errorf("文字数 = %d (%d〜%d), want ok=%v", n, tt.rule.MinLength, tt.rule.MaxLength, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTagPattern(t *testing.T) {
+	rules := DefaultRules().Tag
+	tests := map[string]bool{
+		"Go":      true,
+		"日本語":     true,
+		"a,b":     false,
+		"改行\nあり":  false,
+		"タブ\tあり":  false,
+		"スペース あり": true,
+	}
+	for name, want := range tests {
+		if got := rules.MatchPattern(name); got != want {
+			t.Errorf("MatchPattern(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "rules.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := LoadRules(write(`{"book": {"name": {"max_length": 80, "normalization": "NFKC"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Book.Name.MaxLength != 80 || rules.Book.Name.Normalization != "NFKC" || rules.Author.Name.MaxLength != 100 {
+		t.Errorf("rules = %+v, want ファイルの値とデフォルトのルールを組み合わせたルール", rules)
+	}
+
+	for _, content := range []string{
+		`{"book": {"name": {"normalization": "NFD"}}}`,
+		`{"author": {"name": {"min_length": 10, "max_length": 5}}}`,
+		`{"tag": {"pattern": "["}}`,
+		`{"book": {"price": {"min": 100, "max": 10}}}`,
+		`{`,
+	} {
+		if _, err := LoadRules(write(content)); err == nil {
+			t.Errorf("LoadRules(%s) がエラーになりませんでした", content)
+		}
+	}
+}
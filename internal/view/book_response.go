@@ -0,0 +1,97 @@
+package views
+
+// レスポンスの result に設定する本の型
+// JSON の項目名と null になる項目はこの型で決まるため、変更する場合は
+// internal/controller/testdata のゴールデンファイルも更新すること
+
+// BookResponse は本のレスポンス
+// 値がない項目 (isbn など) は null で返す
+// authors・tags は一覧で embed を指定した場合と詳細でのみ返し、lowest_price は詳細でのみ返す
+type BookResponse struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Price           int               `json:"price"`
+	ISBN            *string           `json:"isbn"`
+	Status          string            `json:"status"`
+	StatusChangedAt *string           `json:"status_changed_at"`
+	StatusChangedBy *string           `json:"status_changed_by"`
+	CreatedAt       string            `json:"created_at"`
+	UpdatedAt       string            `json:"updated_at"`
+	Version         int               `json:"version"`
+	DeletedAt       *string           `json:"deleted_at"`
+	Authors         *[]AuthorResponse `json:"authors,omitempty"`
+	Tags            *[]string         `json:"tags,omitempty"`
+	LowestPrice     *int              `json:"lowest_price,omitempty"`
+}
+
+// BookListResponse は本の一覧のレスポンス
+type BookListResponse struct {
+	Books []BookResponse `json:"books"`
+}
+
+// BookCreatedResponse は本の登録結果のレスポンス
+// 登録した本の詳細と同じ内容で、Location ヘッダーの URL でも同じ本を取得できる
+type BookCreatedResponse struct {
+	BookResponse
+}
+
+// BookExportResponse はエクスポート (NDJSON・JSON) の1件分
+// 一覧の項目とタグに加えて、取り込みと同じ形式の著者IDを含める
+type BookExportResponse struct {
+	BookResponse
+	AuthorIDs []string `json:"author_ids"`
+}
+
+// AuthorResponse は著者のレスポンス
+type AuthorResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+// PriceHistoryResponse は本の価格の変更履歴のレスポンス
+type PriceHistoryResponse struct {
+	BookID       string                `json:"book_id"`
+	CurrentPrice int                   `json:"current_price"`
+	LowestPrice  int                   `json:"lowest_price"`
+	History      []PriceChangeResponse `json:"history"`
+}
+
+// PriceChangeResponse は価格の変更1件分
+// 登録時の価格は old_price を null で返す
+type PriceChangeResponse struct {
+	OldPrice  *int   `json:"old_price"`
+	NewPrice  int    `json:"new_price"`
+	TrnID     string `json:"trn_id"`
+	ChangedAt string `json:"changed_at"`
+}
+
+// BookStatsResponse は購入ステータスごとの本の数のレスポンス
+// by_status にはすべてのステータスを含める (本がないステータスは 0)
+type BookStatsResponse struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// PriceSummaryResponse は本の件数と価格の集計結果
+type PriceSummaryResponse struct {
+	Count   int     `json:"count"`
+	Total   int     `json:"total"`
+	Average float64 `json:"average"`
+	Min     int     `json:"min"`
+	Max     int     `json:"max"`
+}
+
+// PriceSummaryGroupResponse はグループごとの集計結果
+type PriceSummaryGroupResponse struct {
+	Key string `json:"key"`
+	PriceSummaryResponse
+}
+
+// PriceSummaryListResponse は価格の集計のレスポンス
+// group_by を指定しない場合は group_by・groups を返さない
+type PriceSummaryListResponse struct {
+	Summary PriceSummaryResponse         `json:"summary"`
+	GroupBy string                       `json:"group_by,omitempty"`
+	Groups  *[]PriceSummaryGroupResponse `json:"groups,omitempty"`
+}
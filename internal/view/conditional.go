@@ -0,0 +1,153 @@
+package views
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag はレスポンスの内容と形式から強い ETag を作成する
+// 内容が同じであれば、リクエストごとに異なる trn_id などに関係なく同じ値になる
+// 形式 (ctx に設定されたメディアタイプ) が異なる表現はバイト列が異なるため、異なる値になる
+func ETag(ctx context.Context, payload interface{}) string {
+	hash := contentHash(ctx, payload)
+	if hash == "" {
+		return ""
+	}
+	return `"` + hash + `"`
+}
+
+// VersionedETag はバージョンとレスポンスの内容から強い ETag ("<バージョン>-<ハッシュ>") を作成する
+// If-Match で受け取った ETag からは ETagVersion で更新前のバージョンを取り出せる
+func VersionedETag(ctx context.Context, version int, payload interface{}) string {
+	hash := contentHash(ctx, payload)
+	if hash == "" {
+		return ""
+	}
+	return `"` + strconv.Itoa(version) + "-" + hash + `"`
+}
+
+// ETagVersion は VersionedETag で作成した ETag (圧縮した表現の ETag を含む) からバージョンを取り出す
+// If-Match では強い比較を行うため、弱い ETag (W/"...") やバージョンを含まない ETag の場合は false を返す
+func ETagVersion(etag string) (int, bool) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+	v, _, ok := strings.Cut(etag[1:len(etag)-1], "-")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// 圧縮したレスポンスの content-coding。CompressionMiddleware が対応している形式と同じ
+var contentCodings = []string{"br", "gzip"}
+
+// EncodedETag は content-coding で圧縮した表現の強い ETag を返す ("3-abc" → "3-abc-br")
+// 圧縮した表現はバイト列が異なるため、圧縮前と異なる強い ETag にする。弱い ETag はそのまま返す
+func EncodedETag(etag, coding string) string {
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// 圧縮した表現の ETag から、圧縮前の表現の ETag を取り出す
+func identityETag(etag string) string {
+	for _, coding := range contentCodings {
+		suffix := "-" + coding + `"`
+		if strings.HasSuffix(etag, suffix) {
+			return etag[:len(etag)-len(suffix)] + `"`
+		}
+	}
+	return etag
+}
+
+// レスポンスの内容を JSON に変換したハッシュを返す
+// デフォルト (JSON) 以外の形式では、メディアタイプもハッシュに含める
+func contentHash(ctx context.Context, payload interface{}) string {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	if mediaType, _ := ctx.Value(encoderKey{}).(string); mediaType != "" && mediaType != defaultMediaType {
+		data = append([]byte(mediaType+"\n"), data...)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// SetCacheValidators は ETag と Last-Modified ヘッダーを設定する
+// 空の ETag とゼロ値の日時は設定しない
+func SetCacheValidators(w http.ResponseWriter, etag string, lastModified time.Time) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// CheckNotModified は ETag と Last-Modified ヘッダーを設定し、条件付き GET の条件を評価する
+// クライアントのキャッシュが最新の場合は 304 を返して true を返す
+// If-None-Match が指定されている場合は If-Modified-Since より優先する
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	SetCacheValidators(w, etag, lastModified)
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = etagListMatches(inm, etag, false)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			notModified = !lastModified.Truncate(time.Second).After(t)
+		}
+	}
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// MatchesIfMatch は If-Match ヘッダーの値が現在の ETag と一致するかどうかを判定する
+// RFC 9110 に従って強い比較を行う。圧縮した表現の ETag (EncodedETag) も現在の表現の ETag として一致させる
+func MatchesIfMatch(ifMatch, etag string) bool {
+	return etagListMatches(ifMatch, etag, true)
+}
+
+// カンマ区切りの ETag のリストに etag (またはその圧縮した表現の ETag) が含まれるかどうかを判定する
+// strong が true の場合は強い比較 (弱い ETag は一致しない)、false の場合は弱い比較 (W/ を無視) を行う
+func etagListMatches(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	current := identityETag(strings.TrimPrefix(etag, "W/"))
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if strong {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if identityETag(candidate) == current {
+			return true
+		}
+	}
+	return false
+}
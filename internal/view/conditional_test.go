@@ -0,0 +1,69 @@
+package views
+
+import "testing"
+
+func TestEncodedETag(t *testing.T) {
+	tests := map[string]string{
+		`"3-abc"`:   `"3-abc-br"`,
+		`"abc"`:     `"abc-br"`,
+		`W/"3-abc"`: `W/"3-abc"`,
+		``:          ``,
+	}
+	for etag, want := range tests {
+		if got := EncodedETag(etag, "br"); got != want {
+			t.Errorf("EncodedETag(%q) = %q, want %q", etag, got, want)
+		}
+	}
+}
+
+func TestETagVersion(t *testing.T) {
+	tests := []struct {
+		etag    string
+		version int
+		ok      bool
+	}{
+		{`"3-abc"`, 3, true},
+		{`"3-abc-gzip"`, 3, true},
+		{` "12-abc" `, 12, true},
+		{`W/"3-abc"`, 0, false},
+		{`"abc"`, 0, false},
+		{`"0-abc"`, 0, false},
+		{`3-abc`, 0, false},
+	}
+	for _, tt := range tests {
+		version, ok := ETagVersion(tt.etag)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("ETagVersion(%q) = %d, %v, want %d, %v", tt.etag, version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		list      string
+		etag      string
+		ifMatch   bool // MatchesIfMatch (強い比較) の結果
+		noneMatch bool // If-None-Match (弱い比較) の結果
+	}{
+		{"同じ ETag", `"3-abc"`, `"3-abc"`, true, true},
+		{"圧縮した表現の ETag", `"3-abc-br"`, `"3-abc"`, true, true},
+		{"別の形式で圧縮した表現の ETag", `"3-abc-gzip"`, `"3-abc-br"`, true, true},
+		{"弱い ETag", `W/"3-abc"`, `"3-abc"`, false, true},
+		{"現在の ETag が弱い ETag", `"3-abc"`, `W/"3-abc"`, false, true},
+		{"リストの中の ETag", `"1-xyz", "3-abc"`, `"3-abc"`, true, true},
+		{"ワイルドカード", `*`, `"3-abc"`, true, true},
+		{"異なる ETag", `"2-abc"`, `"3-abc"`, false, false},
+		{"ETag がない", `*`, ``, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesIfMatch(tt.list, tt.etag); got != tt.ifMatch {
+				t.Errorf("MatchesIfMatch(%q, %q) = %v, want %v", tt.list, tt.etag, got, tt.ifMatch)
+			}
+			if got := etagListMatches(tt.list, tt.etag, false); got != tt.noneMatch {
+				t.Errorf("etagListMatches(%q, %q, false) = %v, want %v", tt.list, tt.etag, got, tt.noneMatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package views
+
+import (
+	"context"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Encoder はレスポンスを1つの形式で書き出す
+// Response・ExceptionResponse をそのまま受け取り、どの形式でも同じ構造で出力する
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, response interface{}) error
+}
+
+// 登録されたエンコーダー
+type registeredEncoder struct {
+	mediaType string
+	encoder   Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   []registeredEncoder
+)
+
+// デフォルトの形式。Accept ヘッダーがない場合や、どの形式でもよい場合に使用する
+const defaultMediaType = "application/json"
+
+func init() {
+	RegisterEncoder("application/json", jsonEncoder{})
+	RegisterEncoder("application/xml", xmlEncoder{})
+	RegisterEncoder("text/xml", xmlEncoder{})
+	RegisterEncoder("application/msgpack", msgpackEncoder{})
+	RegisterEncoder("application/x-msgpack", msgpackEncoder{})
+	RegisterEncoder("text/csv", csvEncoder{})
+}
+
+// RegisterEncoder はメディアタイプに対応するエンコーダーを登録する
+// Accept ヘッダーで同じ優先度の形式が複数ある場合は、先に登録した形式を使用する
+// 同じメディアタイプを再度登録した場合はエンコーダーを置き換える
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	mediaType = strings.ToLower(mediaType)
+	for i := range encoders {
+		if encoders[i].mediaType == mediaType {
+			encoders[i].encoder = encoder
+			return
+		}
+	}
+	encoders = append(encoders, registeredEncoder{mediaType: mediaType, encoder: encoder})
+}
+
+// EncoderMediaTypes は登録されているメディアタイプを登録順に返す
+func EncoderMediaTypes() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	types := make([]string, len(encoders))
+	for i, e := range encoders {
+		types[i] = e.mediaType
+	}
+	return types
+}
+
+// Accept ヘッダーの1つのメディアレンジ
+type acceptRange struct {
+	mediaType string // "type/subtype"、"type/*" または "*/*"
+	q         float64
+}
+
+// Accept ヘッダーを解析する
+// メディアタイプが不正なメディアレンジ (例: "json"、"*/xml") は無視する
+// パラメータの形式が不正な場合もメディアタイプは有効とし、解析できない q は 1 として扱う
+// (メディアレンジ全体を無視すると、受け入れ可能な形式があっても 406 になるため)
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok || !isToken(typ) || !isToken(subtype) || (typ == "*" && subtype != "*") {
+			continue
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: parseQuality(params)})
+	}
+	return ranges
+}
+
+// メディアレンジのパラメータから品質値 (q) を取り出す
+// q がない場合や解析できない場合は 1、範囲外の場合は 0〜1 に収める
+func parseQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(value), `"`), 64)
+		if err != nil {
+			return 1
+		}
+		return math.Max(0, math.Min(1, q))
+	}
+	return 1
+}
+
+// メディアタイプの type・subtype として正しい文字列かどうかを判定する (RFC 9110 の token)
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || r <= ' ' || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// メディアタイプに一致するメディアレンジのうち、最も具体的なものの品質値を返す
+// 一致するメディアレンジがない場合は 0 を返す
+func qualityOf(mediaType string, ranges []acceptRange) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	best, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case r.mediaType == typ+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			best, specificity = r.q, s
+		}
+	}
+	return best
+}
+
+// NegotiateEncoder は Accept ヘッダーから使用するエンコーダーのメディアタイプを選ぶ
+// 品質値 (q) が最も高い形式を選び、同じ場合は登録順を優先する
+// Accept ヘッダーが空の場合はデフォルトの形式 (JSON) を返す
+// 受け入れ可能な形式がない場合は ok に false を返す
+func NegotiateEncoder(accept string) (mediaType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return defaultMediaType, true
+	}
+	ranges := parseAccept(accept)
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	best := 0.0
+	for _, e := range encoders {
+		if q := qualityOf(e.mediaType, ranges); q > best {
+			mediaType, best = e.mediaType, q
+		}
+	}
+	return mediaType, best > 0
+}
+
+type encoderKey struct{}
+
+// WithEncoder はレスポンスの形式をコンテキストに設定する
+func WithEncoder(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, encoderKey{}, mediaType)
+}
+
+// コンテキストに設定された形式のエンコーダーを返す
+// 設定されていない場合はデフォルトの形式 (JSON) のエンコーダーを返す
+func encoderFromContext(ctx context.Context) Encoder {
+	mediaType, _ := ctx.Value(encoderKey{}).(string)
+	if mediaType == "" {
+		mediaType = defaultMediaType
+	}
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	for _, e := range encoders {
+		if e.mediaType == mediaType {
+			return e.encoder
+		}
+	}
+	return jsonEncoder{}
+}
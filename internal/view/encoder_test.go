@@ -0,0 +1,376 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"空", "", "application/json", true},
+		{"JSON", "application/json", "application/json", true},
+		{"大文字小文字", "Application/XML", "application/xml", true},
+		{"全て", "*/*", "application/json", true},
+		{"type のワイルドカード", "text/*", "text/xml", true},
+		{"type のワイルドカードより具体的な形式を優先", "text/*;q=0.5, text/csv", "text/csv", true},
+		{"q が高い形式", "application/json;q=0.5, application/msgpack", "application/msgpack", true},
+		{"q が同じ場合は登録順", "application/xml, application/json", "application/json", true},
+		{"より具体的なメディアレンジを優先", "*/*;q=0, application/xml", "application/xml", true},
+		{"q=0 は受け入れない", "application/json;q=0", "", false},
+		{"q=0 の形式を除く", "application/json;q=0, */*;q=0.1", "application/xml", true},
+		{"q=0.000", "application/json;q=0.000, text/csv;q=0.001", "text/csv", true},
+		{"空白", " application/xml ; q=1 , application/json ; q=0.2 ", "application/xml", true},
+		{"未対応の形式", "text/html", "", false},
+		{"不正な q は 1 として扱う", "application/xml;q=abc", "application/xml", true},
+		{"範囲外の q は 1 に収める", "application/xml;q=5, application/json;q=0.9", "application/xml", true},
+		{"負の q は 0 に収める", "application/xml;q=-1, text/csv;q=0.1", "text/csv", true},
+		{"不正なパラメータ", "application/xml;charset, application/json;q=0.1", "application/xml", true},
+		{"引用符で閉じていないパラメータ", `application/msgpack;foo="bar, application/json;q=0.1`, "application/msgpack", true},
+		{"不正なメディアタイプは無視する", "json, application/xml;q=0.5", "application/xml", true},
+		{"*/subtype は無視する", "*/xml", "", false},
+		{"空の要素", ",, text/csv", "text/csv", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NegotiateEncoder(tt.accept)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("NegotiateEncoder(%q) = %q, %v, want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+type testBook struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Price float64  `json:"price"`
+	Stock int      `json:"stock"`
+	Note  *string  `json:"note"`
+	Tags  []string `json:"tags"`
+}
+
+type testResponse struct {
+	TrnID  string `json:"trn_id"`
+	Result struct {
+		Books []testBook `json:"books"`
+	} `json:"result"`
+}
+
+func newTestResponse() testResponse {
+	note := "日本語のメモ & <特殊文字>"
+	var res testResponse
+	res.TrnID = "00000000-0000-0000-0000-000000000000"
+	res.Result.Books = []testBook{
+		{ID: 1, Name: "=SUM(A1:A2)", Price: 1980.5, Stock: -3, Note: &note, Tags: []string{"go", "-web"}},
+		{ID: 70000, Name: strings.Repeat("長い書名", 20), Price: 0, Stock: 300, Tags: []string{}},
+	}
+	return res
+}
+
+// XML に変換して読み戻すと、元のレスポンスと同じ内容になる
+func TestXMLEncoderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (xmlEncoder{}).Encode(&buf, newTestResponse()); err != nil {
+		t.Fatal(err)
+	}
+
+	type xmlNote struct {
+		Nil   string `xml:"nil,attr"`
+		Value string `xml:",chardata"`
+	}
+	var got struct {
+		XMLName xml.Name `xml:"response"`
+		TrnID   string   `xml:"trn_id"`
+		Books   []struct {
+			ID    int      `xml:"id"`
+			Name  string   `xml:"name"`
+			Price float64  `xml:"price"`
+			Stock int      `xml:"stock"`
+			Note  xmlNote  `xml:"note"`
+			Tags  []string `xml:"tags>item"`
+		} `xml:"result>books>item"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("XML を解析できません: %v\n%s", err, buf.String())
+	}
+
+	want := newTestResponse()
+	if got.TrnID != want.TrnID || len(got.Books) != len(want.Result.Books) {
+		t.Fatalf("XML = %s", buf.String())
+	}
+	for i, b := range want.Result.Books {
+		g := got.Books[i]
+		if g.ID != b.ID || g.Name != b.Name || g.Price != b.Price || g.Stock != b.Stock || len(g.Tags) != len(b.Tags) {
+			t.Errorf("books[%d] = %+v, want %+v", i, g, b)
+		}
+		for j := range b.Tags {
+			if g.Tags[j] != b.Tags[j] {
+				t.Errorf("books[%d].tags[%d] = %q, want %q", i, j, g.Tags[j], b.Tags[j])
+			}
+		}
+		if b.Note == nil && (g.Note.Nil != "true" || g.Note.Value != "") {
+			t.Errorf("books[%d].note = %+v, want nil=\"true\"", i, g.Note)
+		}
+		if b.Note != nil && (g.Note.Nil != "" || g.Note.Value != *b.Note) {
+			t.Errorf("books[%d].note = %+v, want %q", i, g.Note, *b.Note)
+		}
+	}
+}
+
+// XML の要素名に使えないキーは <entry key="..."> で出力する
+func TestXMLEncoderInvalidName(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (xmlEncoder{}).Encode(&buf, map[string]int{"1st": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<entry key="1st">1</entry>`) {
+		t.Errorf("XML = %s", buf.String())
+	}
+}
+
+// MessagePack に変換して読み戻すと、JSON に変換した場合と同じ値になる
+func TestMsgpackEncoderRoundTrip(t *testing.T) {
+	response := map[string]interface{}{
+		"response": newTestResponse(),
+		"numbers":  []interface{}{0, 127, 128, -1, -32, -33, -129, 40000, -40000, 1 << 40, -(1 << 40), 0.25, true, false},
+		"long":     strings.Repeat("a", 300),
+		"longer":   strings.Repeat("b", 70000),
+		"array":    make([]int, 20),
+	}
+
+	var buf bytes.Buffer
+	if err := (msgpackEncoder{}).Encode(&buf, response); err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(buf.Bytes())
+	got, err := readMsgpack(r)
+	if err != nil {
+		t.Fatalf("MessagePack を解析できません: %v", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("%d バイトが残っています", r.Len())
+	}
+
+	data, _ := json.Marshal(response)
+	var want interface{}
+	json.Unmarshal(data, &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MessagePack の値 = %v, want %v", got, want)
+	}
+}
+
+// テスト用の MessagePack のデコーダー
+// json.Unmarshal と比較できるよう、数値は float64、マップは map[string]interface{} で返す
+func readMsgpack(r *bytes.Reader) (interface{}, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case code <= 0x7f:
+		return float64(code), nil
+	case code >= 0xe0:
+		return float64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return readMsgpackString(r, int(code&0x1f))
+	case code&0xf0 == 0x90:
+		return readMsgpackArray(r, int(code&0x0f))
+	case code&0xf0 == 0x80:
+		return readMsgpackMap(r, int(code&0x0f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcb:
+		var bits uint64
+		err := binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xd0:
+		var n int8
+		err := binary.Read(r, binary.BigEndian, &n)
+		return float64(n), err
+	case 0xd1:
+		var n int16
+		err := binary.Read(r, binary.BigEndian, &n)
+		return float64(n), err
+	case 0xd2:
+		var n int32
+		err := binary.Read(r, binary.BigEndian, &n)
+		return float64(n), err
+	case 0xd3:
+		var n int64
+		err := binary.Read(r, binary.BigEndian, &n)
+		return float64(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(r, code-0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, code-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, n)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, code-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, n)
+	}
+	return nil, fmt.Errorf("未対応の形式: 0x%02x", code)
+}
+
+// 長さを読み込む。size は 0 が 8 ビット、1 が 16 ビット、2 が 32 ビット
+func readMsgpackLength(r *bytes.Reader, size byte) (int, error) {
+	switch size {
+	case 0:
+		n, err := r.ReadByte()
+		return int(n), err
+	case 1:
+		var n uint16
+		err := binary.Read(r, binary.BigEndian, &n)
+		return int(n), err
+	default:
+		var n uint32
+		err := binary.Read(r, binary.BigEndian, &n)
+		return int(n), err
+	}
+}
+
+func readMsgpackString(r *bytes.Reader, n int) (interface{}, error) {
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func readMsgpackArray(r *bytes.Reader, n int) (interface{}, error) {
+	arr := make([]interface{}, n)
+	for i := range arr {
+		value, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = value
+	}
+	return arr, nil
+}
+
+func readMsgpackMap(r *bytes.Reader, n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("キーが文字列ではありません: %v", key)
+		}
+		if m[s], err = readMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// CSV に変換して読み戻すと、要素ごとに1行で、文字列のセルは数式にならないよう ' が付く
+func TestCSVEncoderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (csvEncoder{}).Encode(&buf, newTestResponse()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV を解析できません: %v", err)
+	}
+
+	want := [][]string{
+		{"trn_id", "id", "name", "price", "stock", "note", "tags"},
+		{"00000000-0000-0000-0000-000000000000", "1", "'=SUM(A1:A2)", "1980.5", "-3", "日本語のメモ & <特殊文字>", `["go","-web"]`},
+		{"00000000-0000-0000-0000-000000000000", "70000", strings.Repeat("長い書名", 20), "0", "300", "", "[]"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %q, want %q", records, want)
+	}
+}
+
+// result がオブジェクトの場合は1行で出力する
+func TestCSVEncoderObject(t *testing.T) {
+	var buf bytes.Buffer
+	response := map[string]interface{}{"result": map[string]interface{}{"count": 2, "name": "@user"}}
+	if err := (csvEncoder{}).Encode(&buf, response); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"count", "name"}, {"2", "'@user"}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %q, want %q", records, want)
+	}
+}
+
+func TestSafeCSVCell(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"Go":       "Go",
+		"=1+1":     "'=1+1",
+		"+81":      "'+81",
+		"-1":       "'-1",
+		"@SUM(A1)": "'@SUM(A1)",
+		"\tcmd":    "'\tcmd",
+		"\rcmd":    "'\rcmd",
+		"a=b":      "a=b",
+		"日本語=1":    "日本語=1",
+	}
+	for in, want := range tests {
+		if got := SafeCSVCell(in); got != want {
+			t.Errorf("SafeCSVCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// 同じ内容でも形式ごとに異なる ETag になり、JSON の ETag は形式を設定しない場合と同じになる
+func TestETagIncludesMediaType(t *testing.T) {
+	payload := newTestResponse()
+	jsonETag := ETag(WithEncoder(context.Background(), "application/json"), payload)
+	if jsonETag != ETag(context.Background(), payload) {
+		t.Errorf("JSON の ETag = %s, want 形式を設定しない場合と同じ", jsonETag)
+	}
+	seen := map[string]string{jsonETag: "application/json"}
+	for _, mediaType := range []string{"application/xml", "application/msgpack", "text/csv"} {
+		ctx := WithEncoder(context.Background(), mediaType)
+		for _, etag := range []string{ETag(ctx, payload), VersionedETag(ctx, 1, payload)} {
+			if other, ok := seen[etag]; ok {
+				t.Errorf("%s の ETag %s が %s と同じです", mediaType, etag, other)
+			}
+			seen[etag] = mediaType
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package views
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// jsonEncoder は JSON で書き出す
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (jsonEncoder) Encode(w io.Writer, response interface{}) error {
+	return json.NewEncoder(w).Encode(response)
+}
+
+// JSON 以外の形式は、レスポンスを一度 JSON の値 (オブジェクトはキーの順序を保持) に変換してから書き出す
+// これにより json タグや時刻の形式など、JSON と同じ内容・構造で出力する
+
+// objectField はキーの順序を保持したオブジェクトの1つの項目
+type objectField struct {
+	key   string
+	value interface{}
+}
+
+// object はキーの順序を保持した JSON のオブジェクト
+type object []objectField
+
+// MarshalJSON はキーの順序を保持して JSON に変換する
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// レスポンスを JSON の値に変換する
+// 値は nil, bool, json.Number, string, []interface{}, object のいずれか
+func toTree(response interface{}) (interface{}, error) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeTree(dec)
+}
+
+func decodeTree(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, objectField{key: key.(string), value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	default:
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+}
+
+// xmlEncoder は XML で書き出す
+// ルート要素は <response> で、配列の要素は <item>、XML の要素名に使えないキーは <entry key="..."> で表す
+// null は nil="true" 属性を付けた空の要素で表す
+type xmlEncoder struct{}
+
+func (xmlEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (xmlEncoder) Encode(w io.Writer, response interface{}) error {
+	tree, err := toTree(response)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXML(enc, "response", tree); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func writeXML(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}}
+	}
+	if value == nil {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case object:
+		for _, f := range v {
+			if err := writeXML(enc, f.key, f.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXML(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(scalarString(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// XML の要素名として使用できるかどうかを判定する
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// JSON のスカラー値を文字列にする
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// msgpackEncoder は MessagePack で書き出す
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+func (msgpackEncoder) Encode(w io.Writer, response interface{}) error {
+	tree, err := toTree(response)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writeMsgpack(&buf, tree)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeMsgpack(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			writeMsgpackInt(buf, n)
+		} else {
+			f, _ := v.Float64()
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			writeMsgpack(buf, item)
+		}
+	case object:
+		writeMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, f := range v {
+			writeMsgpack(buf, f.key)
+			writeMsgpack(buf, f.value)
+		}
+	}
+}
+
+// 文字列・配列・マップの長さを書き出す
+// fixLimit 未満は fix 形式、それ以外は長さに応じて 8・16・32 ビットの形式を使う (code8 が 0 の場合は 8 ビットの形式はない)
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case n < fixLimit:
+		buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= 127:
+		buf.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// 表計算ソフトで数式として解釈される先頭の文字
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// SafeCSVCell は表計算ソフトで開いたときに数式として実行されないよう、
+// 数式として解釈される文字 (= + - @ タブ 改行) で始まるセルの先頭に ' を付ける
+func SafeCSVCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvEncoder は CSV で書き出す
+// result がオブジェクトの配列、または配列を1つだけ含むオブジェクト (例: {"books": [...]}) の場合は要素ごとに1行、
+// それ以外の場合は result の項目を1行で出力する。各行の先頭には trn_id と trn_time を出力する
+// 入れ子になった値は JSON の文字列として1つのセルに出力する
+type csvEncoder struct{}
+
+func (csvEncoder) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (csvEncoder) Encode(w io.Writer, response interface{}) error {
+	tree, err := toTree(response)
+	if err != nil {
+		return err
+	}
+	envelope, _ := tree.(object)
+	var prefix object
+	var result interface{}
+	for _, f := range envelope {
+		if f.key == "result" {
+			result = f.value
+		} else {
+			prefix = append(prefix, f)
+		}
+	}
+
+	rows := csvRows(result)
+	// 列は出現した順に並べる
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, f := range row {
+			if !seen[f.key] {
+				seen[f.key] = true
+				columns = append(columns, f.key)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, len(prefix)+len(columns))
+	for _, f := range prefix {
+		header = append(header, f.key)
+	}
+	cw.Write(append(header, columns...))
+	for _, row := range rows {
+		values := map[string]interface{}{}
+		for _, f := range row {
+			values[f.key] = f.value
+		}
+		record := make([]string, 0, len(header)+len(columns))
+		for _, f := range prefix {
+			record = append(record, csvCell(f.value))
+		}
+		for _, c := range columns {
+			record = append(record, csvCell(values[c]))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CSV のセルの値に変換する。数値 (負の数) はそのまま、文字列は SafeCSVCell で数式にならないようにする
+func csvCell(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return SafeCSVCell(s)
+	}
+	return scalarString(value)
+}
+
+// result を CSV の行に分ける
+func csvRows(result interface{}) []object {
+	switch v := result.(type) {
+	case []interface{}:
+		return arrayRows(v)
+	case object:
+		var list []interface{}
+		lists := 0
+		for _, f := range v {
+			if arr, ok := f.value.([]interface{}); ok {
+				list = arr
+				lists++
+			}
+		}
+		if lists == 1 && len(v) == 1 {
+			return arrayRows(list)
+		}
+		return []object{v}
+	default:
+		return []object{{{key: "result", value: v}}}
+	}
+}
+
+// 配列の要素をそれぞれ1行にする。オブジェクト以外の要素は value 列に出力する
+func arrayRows(list []interface{}) []object {
+	rows := make([]object, len(list))
+	for i, item := range list {
+		if obj, ok := item.(object); ok {
+			rows[i] = obj
+		} else {
+			rows[i] = object{{key: "value", value: item}}
+		}
+	}
+	return rows
+}
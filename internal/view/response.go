@@ -2,10 +2,11 @@ package views
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 
 	errors "github.com/HwaI12/go-api-tutorial/internal/error"
+	"github.com/HwaI12/go-api-tutorial/internal/metrics"
+	"github.com/HwaI12/go-api-tutorial/internal/tracing"
 	"github.com/HwaI12/go-api-tutorial/internal/transaction"
 )
 
@@ -56,20 +57,32 @@ func CreateExceptionResponse(ctx context.Context, exception *errors.UserDefinedE
 // エラーレスポンスを返す
 // エラーレスポンスはエラーコードとエラーメッセージを含む
 // エラーコードとエラーメッセージはユーザー定義エラーから取得
-// エラーレスポンスはJSON形式で返す
+// エラーレスポンスは Accept ヘッダーで選ばれた形式 (デフォルト: JSON) で返す
 func RespondWithError(w http.ResponseWriter, ctx context.Context, err *errors.UserDefinedError) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(err.HTTPStatusCode)
+	metrics.ErrorsTotal.Inc(err.ErrorCode)
 	response := CreateExceptionResponse(ctx, err)
-	json.NewEncoder(w).Encode(response)
+	encode(ctx, w, err.HTTPStatusCode, response)
 }
 
-// JSON形式のレスポンスを返す
-// レスポンスは正常な場合とエラーの場合がある
-// レスポンスはJSON形式で返す
+// 正常なレスポンスを返す
+// レスポンスは Accept ヘッダーで選ばれた形式 (デフォルト: JSON) で返す
+// 関数名は JSON のみに対応していたときのもの
 func RespondWithJSON(w http.ResponseWriter, ctx context.Context, statusCode int, payload interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
 	response := CreateResponse(ctx, payload)
-	json.NewEncoder(w).Encode(response)
+	encode(ctx, w, statusCode, response)
+}
+
+// レスポンスをコンテキストに設定された形式でエンコードして書き出す
+// エンコード処理はスパンとして記録する
+func encode(ctx context.Context, w http.ResponseWriter, statusCode int, response interface{}) {
+	encoder := encoderFromContext(ctx)
+	_, span := tracing.StartSpan(ctx, "view.Encode", tracing.KindInternal)
+	defer span.End()
+	span.SetAttribute("http.response.content_type", encoder.ContentType())
+
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(statusCode)
+	if err := encoder.Encode(w, response); err != nil {
+		span.SetError(err)
+	}
 }
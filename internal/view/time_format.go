@@ -0,0 +1,43 @@
+package views
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HwaI12/go-api-tutorial/internal/config"
+)
+
+// 旧形式の日時フォーマット (タイムゾーンを含まない)
+const legacyTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	// レスポンスの日時を表示するタイムゾーン
+	displayLocation = time.UTC
+	// 旧形式で日時を返すかどうか
+	legacyTimeFormat = false
+)
+
+// 日時フォーマットの初期設定を行う
+// 環境変数 DISPLAY_TIME_ZONE で表示するタイムゾーンを、TIME_FORMAT_LEGACY で旧形式の使用を指定する
+func InitializeTimeFormat() error {
+	legacyTimeFormat = config.GetBool("TIME_FORMAT_LEGACY", false)
+
+	name := config.GetString("DISPLAY_TIME_ZONE", "UTC")
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		displayLocation = time.UTC
+		return fmt.Errorf("タイムゾーン'%s'の読み込みに失敗しました: %v", name, err)
+	}
+	displayLocation = loc
+	return nil
+}
+
+// 日時をレスポンス用の文字列に変換する
+// 通常は表示タイムゾーンでの RFC 3339 形式、互換モードでは旧形式を返す
+func FormatTime(t time.Time) string {
+	t = t.In(displayLocation)
+	if legacyTimeFormat {
+		return t.Format(legacyTimeLayout)
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Config はデータベースの接続・コネクションプール・トランザクションの設定
+// このパッケージは環境変数を読み込まないため、呼び出し側で作成して Connect に渡す
+type Config struct {
+	// 接続先
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Name     string
+
+	ParseTime      bool   // DATETIME/TIMESTAMP を time.Time として読み込むか
+	Charset        string // 文字セット
+	Collation      string // 照合順序。空文字の場合はドライバのデフォルト
+	TLS            string // true / false / skip-verify / preferred
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+
+	// コネクションプール
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// 接続時の Ping の再試行回数と、再試行までの待機時間 (指数バックオフ)
+	PingRetries    int
+	PingBackoff    time.Duration
+	PingMaxBackoff time.Duration
+
+	// RunInTx でデッドロックが発生した場合の再試行回数と、再試行までの待機時間
+	DeadlockRetries int
+	DeadlockBackoff time.Duration
+
+	// WithTimeout で設定するクエリのタイムアウト
+	// OperationTimeouts に指定した操作 (例: "get_books") はその値を優先する。0 以下の場合はタイムアウトを設定しない
+	QueryTimeout      time.Duration
+	OperationTimeouts map[string]time.Duration
+
+	// ログの出力先。nil の場合はログを出力しない
+	Logger Logger
+}
+
+// Logger はこのパッケージのログの出力先
+// ctx にはリクエストのコンテキストを渡すため、トランザクションIDなどを付けて出力できる
+type Logger interface {
+	Infof(ctx context.Context, format string, args ...interface{})
+	Warnf(ctx context.Context, format string, args ...interface{})
+}
+
+// ログを出力しない Logger
+type nopLogger struct{}
+
+func (nopLogger) Infof(ctx context.Context, format string, args ...interface{}) {}
+func (nopLogger) Warnf(ctx context.Context, format string, args ...interface{}) {}
+
+// DefaultConfig は接続先以外をデフォルト値にした設定を返す
+func DefaultConfig() Config {
+	return Config{
+		ParseTime:       true,
+		Charset:         "utf8mb4",
+		TLS:             "false",
+		ConnectTimeout:  10 * time.Second,
+		MaxOpenConns:    25,
+		MaxIdleConns:    25,
+		ConnMaxLifetime: 5 * time.Minute,
+		ConnMaxIdleTime: time.Minute,
+		PingRetries:     5,
+		PingBackoff:     time.Second,
+		PingMaxBackoff:  30 * time.Second,
+		DeadlockRetries: defaultDeadlockRetries,
+		DeadlockBackoff: defaultDeadlockBackoff,
+		QueryTimeout:    defaultQueryTimeout,
+	}
+}
+
+// RunInTx・WithTimeout・Migrate で使用する設定
+var current atomic.Pointer[Config]
+
+// Configure は RunInTx・WithTimeout・Migrate で使用する設定を変更する
+// Connect は渡された設定でこの関数を呼び出すため、通常は呼び出す必要はない
+func Configure(cfg Config) {
+	if cfg.Logger == nil {
+		cfg.Logger = nopLogger{}
+	}
+	current.Store(&cfg)
+}
+
+// 現在の設定を返す。Configure を呼び出していない場合はデフォルトの設定を返す
+func settings() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	cfg := DefaultConfig()
+	cfg.Logger = nopLogger{}
+	return &cfg
+}
@@ -4,54 +4,109 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/joho/godotenv"
-
-	logger "github.com/HwaI12/go-api-tutorial/internal/log"
+	"github.com/go-sql-driver/mysql"
 )
 
-func Connect(ctx context.Context) (*sql.DB, error) {
-	// トランザクション情報を含むロガーを取得
-	entry := logger.WithTransaction(ctx)
-
-	// .envファイルを読み込む
-	err := godotenv.Load()
-	if err != nil {
-		entry.WithError(err).Error(".envファイルの読み込みに失敗しました")
-		return nil, fmt.Errorf(".envファイルの読み込みに失敗しました: %v", err)
-	}
-
-	// 環境変数が設定されているか確認
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+// Connect は cfg の接続先に接続し、コネクションプールを設定したデータベースを返す
+// cfg は RunInTx・WithTimeout・Migrate の設定としても使用する
+func Connect(ctx context.Context, cfg Config) (*sql.DB, error) {
+	Configure(cfg)
+	log := settings().Logger
 
-	if dbUser == "" || dbPassword == "" || dbName == "" || dbHost == "" || dbPort == "" {
-		entry.Error("必要な環境変数が設定されていません")
-		return nil, fmt.Errorf("必要な環境変数が設定されていません")
+	// 接続先が設定されているか確認
+	if cfg.User == "" || cfg.Password == "" || cfg.Name == "" || cfg.Host == "" || cfg.Port == "" {
+		return nil, fmt.Errorf("データベースの接続先が設定されていません")
 	}
 
-	// データベース接続文字列を作成
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
+	// ドライバの Config 型からデータベース接続文字列を作成
+	mysqlCfg := newMySQLConfig(cfg)
+	dataSourceName := mysqlCfg.FormatDSN()
 
-	entry.Info("データベース接続文字列: ", dataSourceName)
+	log.Infof(ctx, "データベース接続先: %s@%s/%s (parseTime=%t, tls=%s)", mysqlCfg.User, mysqlCfg.Addr, mysqlCfg.DBName, mysqlCfg.ParseTime, mysqlCfg.TLSConfig)
 
 	db, err := sql.Open("mysql", dataSourceName)
 	if err != nil {
-		entry.WithError(err).Error("sql.Openによるデータベース接続に失敗しました")
 		return nil, fmt.Errorf("sql.Openによるデータベース接続に失敗しました: %v", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		entry.WithError(err).Error("db.PingによるデータベースへのPingに失敗しました")
+	// コネクションプールの設定
+	configurePool(db, cfg)
+
+	if err := pingWithRetry(ctx, db, cfg); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("db.PingによるデータベースへのPingに失敗しました: %v", err)
 	}
 
-	entry.Info("データベース接続に成功しました")
+	log.Infof(ctx, "データベース接続に成功しました")
 
 	return db, nil
 }
+
+// 設定から mysql.Config を作成する
+func newMySQLConfig(c Config) *mysql.Config {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = c.Host + ":" + c.Port
+	cfg.DBName = c.Name
+	cfg.ParseTime = c.ParseTime
+	// 日時はセッション・ドライバともに UTC で扱う
+	// 日時のカラムはすべて TIMESTAMP (内部では UTC で保存) のため、セッションのタイムゾーンを変えても既存の値は変わらない
+	// DATETIME のカラムはセッションのタイムゾーンで解釈されるため、追加する場合は UTC の値を保存すること
+	// (手動で作成した books.created_at が DATETIME の場合は、マイグレーション 013 で TIMESTAMP に変換する)
+	cfg.Loc = time.UTC
+	if c.Collation != "" {
+		cfg.Collation = c.Collation
+	}
+	cfg.TLSConfig = c.TLS
+	cfg.Timeout = c.ConnectTimeout
+	cfg.ReadTimeout = c.ReadTimeout
+	cfg.WriteTimeout = c.WriteTimeout
+	cfg.Params = map[string]string{
+		"charset":   c.Charset,
+		"time_zone": "'+00:00'",
+	}
+	return cfg
+}
+
+// コネクションプールを設定する
+func configurePool(db *sql.DB, cfg Config) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// データベースへの Ping を指数バックオフで再試行する
+// 起動直後のデータベースにも接続できるよう、cfg.PingRetries 回まで再試行する
+func pingWithRetry(ctx context.Context, db *sql.DB, cfg Config) error {
+	log := settings().Logger
+	retries, backoff, maxBackoff := cfg.PingRetries, cfg.PingBackoff, cfg.PingMaxBackoff
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := WithTimeout(ctx, "ping")
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return err
+		}
+
+		log.Warnf(ctx, "データベースへのPingに失敗しました。%s後に再試行します (%d/%d): %v", backoff, attempt+1, retries, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
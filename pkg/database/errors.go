@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL のエラー番号
+const (
+	errDuplicateEntry = 1062
+	errLockDeadlock   = 1213
+)
+
+// 一意制約違反のエラーかどうかを判定する
+func IsDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
+
+// デッドロックによりトランザクションが中断されたエラーかどうかを判定する
+func IsDeadlockError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errLockDeadlock
+}
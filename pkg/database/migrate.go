@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// マイグレーションファイル (migrations/NNN_説明.sql) を埋め込む
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// 未適用のマイグレーションを番号順に適用する
+// 適用済みのマイグレーションは schema_migrations テーブルに記録する
+func Migrate(ctx context.Context, db *sql.DB) error {
+	log := settings().Logger
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("schema_migrationsテーブルの作成に失敗しました: %v", err)
+	}
+
+	applied := map[string]bool{}
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("適用済みマイグレーションの取得に失敗しました: %v", err)
+	}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("適用済みマイグレーションのスキャンに失敗しました: %v", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+		if applied[version] {
+			continue
+		}
+
+		log.Infof(ctx, "マイグレーション%sを適用します", version)
+		content, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := applyMigration(ctx, db, string(content)); err != nil {
+			return fmt.Errorf("マイグレーション%sの適用に失敗しました: %v", version, err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations(version) VALUES(?)", version); err != nil {
+			return fmt.Errorf("マイグレーション%sの記録に失敗しました: %v", version, err)
+		}
+		log.Infof(ctx, "マイグレーション%sを適用しました", version)
+	}
+	return nil
+}
+
+// マイグレーションのステートメントを順に実行する
+// MySQL の DDL は暗黙的にコミットされるため、ステートメントごとに実行する
+// ユーザー変数やセッション変数、プリペアドステートメント (SET @v / SET time_zone / PREPARE) を使用できるよう、同じ接続で実行する
+func applyMigration(ctx context.Context, db *sql.DB, content string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for _, stmt := range splitStatements(content) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLファイルの内容を行末のセミコロンで区切り、ステートメントに分割する
+// "--" で始まる行はコメントとして無視する
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, stmt)
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,7 @@
+-- 本のテーブル (既存の環境で手動作成済みの場合は何もしない)
+CREATE TABLE IF NOT EXISTS books (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(100),
+  price INT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,17 @@
+-- タグのテーブル (照合順序により大文字・小文字は区別しない)
+CREATE TABLE IF NOT EXISTS tags (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(50) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_tags_name (name)
+);
+
+-- 本とタグの多対多の関連
+CREATE TABLE IF NOT EXISTS book_tags (
+  book_id INT NOT NULL,
+  tag_id INT NOT NULL,
+  PRIMARY KEY (book_id, tag_id),
+  INDEX idx_book_tags_tag_id (tag_id),
+  CONSTRAINT fk_book_tags_book FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT fk_book_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
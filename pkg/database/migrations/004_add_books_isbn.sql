@@ -0,0 +1,4 @@
+-- 本のISBN (ISBN-13に正規化して保存し、重複を禁止する)
+ALTER TABLE books
+  ADD COLUMN isbn CHAR(13) NULL AFTER price,
+  ADD UNIQUE KEY uq_books_isbn (isbn);
@@ -0,0 +1,6 @@
+-- 購入ステータスと、最後にステータスを変更した日時・変更者
+ALTER TABLE books
+  ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'wanted' AFTER isbn,
+  ADD COLUMN status_changed_at TIMESTAMP NULL AFTER status,
+  ADD COLUMN status_changed_by VARCHAR(100) NULL AFTER status_changed_at,
+  ADD INDEX idx_books_status (status);
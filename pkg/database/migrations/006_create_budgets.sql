@@ -0,0 +1,6 @@
+-- 月ごとの予算
+CREATE TABLE IF NOT EXISTS budgets (
+  month CHAR(7) PRIMARY KEY,
+  amount INT NOT NULL,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
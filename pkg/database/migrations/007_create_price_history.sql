@@ -0,0 +1,11 @@
+-- 本の価格の変更履歴
+CREATE TABLE IF NOT EXISTS price_history (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  book_id INT NOT NULL,
+  old_price INT NULL,
+  new_price INT NOT NULL,
+  trn_id VARCHAR(36) NOT NULL,
+  changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_price_history_book_id (book_id, changed_at),
+  CONSTRAINT fk_price_history_book FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- 論理削除した日時。NULL の場合は削除されていない
+ALTER TABLE books
+  ADD COLUMN deleted_at TIMESTAMP NULL AFTER created_at,
+  ADD INDEX idx_books_deleted_at (deleted_at);
@@ -0,0 +1,16 @@
+-- 書き込み操作の監査ログ
+-- 対象が物理削除された後も残すため、外部キーは設定しない
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  actor VARCHAR(100) NOT NULL,
+  trn_id VARCHAR(36) NOT NULL,
+  route VARCHAR(255) NOT NULL,
+  action VARCHAR(16) NOT NULL,
+  entity VARCHAR(32) NOT NULL,
+  entity_id BIGINT NOT NULL,
+  diff JSON NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_audit_log_entity (entity, entity_id, id),
+  INDEX idx_audit_log_actor (actor, id),
+  INDEX idx_audit_log_created_at (created_at)
+);
@@ -0,0 +1,14 @@
+-- Idempotency-Key ヘッダーで受け付けたリクエストと、その保存したレスポンス
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  actor VARCHAR(100) NOT NULL,
+  idem_key VARCHAR(255) NOT NULL,
+  fingerprint CHAR(64) NOT NULL,
+  state VARCHAR(16) NOT NULL,
+  status_code INT NULL,
+  response_header JSON NULL,
+  response_body MEDIUMBLOB NULL,
+  locked_at TIMESTAMP NULL,
+  expires_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (actor, idem_key),
+  INDEX idx_idempotency_keys_expires_at (expires_at)
+);
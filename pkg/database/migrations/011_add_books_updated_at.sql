@@ -0,0 +1,19 @@
+-- 最後に本を更新した日時
+-- 既存の本はステータスの変更日時または登録日時で初期化する
+-- DDL は暗黙的にコミットされ、途中で失敗するとマイグレーションが記録されないため、再実行できるようにしている
+-- (カラムが既に存在する場合は追加せず、初期化していない本のみ初期化する)
+SET @add_updated_at = IF(
+  EXISTS(SELECT 1 FROM information_schema.COLUMNS
+         WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'books' AND COLUMN_NAME = 'updated_at'),
+  'DO 0',
+  'ALTER TABLE books ADD COLUMN updated_at TIMESTAMP NULL AFTER created_at');
+PREPARE add_updated_at FROM @add_updated_at;
+EXECUTE add_updated_at;
+DEALLOCATE PREPARE add_updated_at;
+-- 手動で作成した books テーブルの created_at が DATETIME の場合は、サーバーのタイムゾーンで保存されている
+-- (TIMESTAMP への変換はマイグレーション 013 で行う)。UTC のセッションでコピーすると日時がずれるため、
+-- コピーの間だけセッションのタイムゾーンをサーバーのタイムゾーンに戻す
+SET time_zone = @@global.time_zone;
+UPDATE books SET updated_at = COALESCE(status_changed_at, created_at) WHERE updated_at IS NULL;
+SET time_zone = '+00:00';
+ALTER TABLE books MODIFY COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
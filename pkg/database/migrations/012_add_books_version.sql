@@ -0,0 +1,2 @@
+-- 楽観的排他制御のためのバージョン。本を更新するたびに 1 ずつ増える
+ALTER TABLE books ADD COLUMN version INT NOT NULL DEFAULT 1 AFTER updated_at;
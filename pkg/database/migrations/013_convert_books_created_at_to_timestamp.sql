@@ -0,0 +1,14 @@
+-- 手動で作成した books テーブルの created_at が DATETIME の場合は TIMESTAMP に変換する
+-- アプリケーションはセッションのタイムゾーンを UTC にして接続するため、DATETIME のままではサーバーのタイムゾーンで保存した日時がずれて読み込まれる
+-- DATETIME の値はサーバーのタイムゾーンで保存されているため、変換の間だけセッションのタイムゾーンをサーバーのタイムゾーンに戻す
+-- (TIMESTAMP への変換ではセッションのタイムゾーンの日時として解釈される)
+SET time_zone = @@global.time_zone;
+SET @convert_created_at = IF(
+  EXISTS(SELECT 1 FROM information_schema.COLUMNS
+         WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'books' AND COLUMN_NAME = 'created_at' AND DATA_TYPE = 'datetime'),
+  'ALTER TABLE books MODIFY COLUMN created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP',
+  'DO 0');
+PREPARE convert_created_at FROM @convert_created_at;
+EXECUTE convert_created_at;
+DEALLOCATE PREPARE convert_created_at;
+SET time_zone = '+00:00';
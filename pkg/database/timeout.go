@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// クエリのデフォルトのタイムアウト
+const defaultQueryTimeout = 5 * time.Second
+
+// 操作ごとのタイムアウトを設定したコンテキストを返す
+// Config.OperationTimeouts に指定した操作 (例: get_books) はその値を優先し、
+// 未設定の場合は Config.QueryTimeout (デフォルト: 5s) を使用する (operationDefaults の操作を除く)
+// 0 以下を指定した場合はタイムアウトを設定しない
+func WithTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout := OperationTimeout(operation)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// QueryTimeout ではなく独自のデフォルト値を使用する操作
+// エクスポートは結果をストリームで返すため、クライアントが切断するまでタイムアウトを設定しない
+var operationDefaults = map[string]time.Duration{
+	"export_books": 0,
+}
+
+// 操作ごとのタイムアウトを返す
+func OperationTimeout(operation string) time.Duration {
+	cfg := settings()
+	if timeout, ok := cfg.OperationTimeouts[strings.ToLower(operation)]; ok {
+		return timeout
+	}
+	if timeout, ok := operationDefaults[operation]; ok {
+		return timeout
+	}
+	return cfg.QueryTimeout
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// デッドロック時の再試行回数と、再試行までの待機時間のデフォルト値
+const (
+	defaultDeadlockRetries = 3
+	defaultDeadlockBackoff = 50 * time.Millisecond
+)
+
+// DBTX は *sql.DB と *sql.Tx に共通するクエリ実行のインターフェース
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type txKey struct{}
+
+// unitOfWork はコンテキストで引き回すトランザクション
+// トランザクション内でデッドロックが発生したかどうかと、コミット後に実行する関数を記録する
+type unitOfWork struct {
+	tx          *sql.Tx
+	deadlock    bool
+	afterCommit []func()
+}
+
+// 発生したエラーがデッドロックの場合は記録する
+func (u *unitOfWork) observe(err error) {
+	if IsDeadlockError(err) {
+		u.deadlock = true
+	}
+}
+
+func (u *unitOfWork) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := u.tx.ExecContext(ctx, query, args...)
+	u.observe(err)
+	return result, err
+}
+
+func (u *unitOfWork) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := u.tx.QueryContext(ctx, query, args...)
+	u.observe(err)
+	return rows, err
+}
+
+func (u *unitOfWork) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := u.tx.QueryRowContext(ctx, query, args...)
+	u.observe(row.Err())
+	return row
+}
+
+func (u *unitOfWork) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := u.tx.PrepareContext(ctx, query)
+	u.observe(err)
+	return stmt, err
+}
+
+// Conn はコンテキストにトランザクションがあればそれを、なければ db を返す
+// モデルの関数はこれを通してクエリを実行し、RunInTx の中では自動的にトランザクションを使用する
+func Conn(ctx context.Context, db DBTX) DBTX {
+	if u, ok := ctx.Value(txKey{}).(*unitOfWork); ok {
+		return u
+	}
+	return db
+}
+
+// InTx はコンテキストにトランザクションがあるかどうかを返す
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*unitOfWork)
+	return ok
+}
+
+// RunInTx はトランザクションを開始し、それを設定したコンテキストで fn を実行する
+// fn がエラーを返した場合やパニックした場合はロールバックし、それ以外はコミットする
+// コンテキストに既にトランザクションがある場合は新しく開始せず、外側のトランザクションに参加する
+//
+// デッドロック (MySQL エラー 1213) が発生した場合は、トランザクション全体を
+// Config.DeadlockRetries 回 (デフォルト: 3) まで再試行する。fn は再試行のたびに呼び出される
+// fn が返したエラーはそのまま返し、開始・コミットの失敗はラップして返す
+func RunInTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+
+	cfg := settings()
+	retries, backoff := cfg.DeadlockRetries, cfg.DeadlockBackoff
+	for attempt := 0; ; attempt++ {
+		deadlock, err := runInTxOnce(ctx, db, fn)
+		if !deadlock || attempt >= retries {
+			return err
+		}
+		wait := backoff * time.Duration(attempt+1)
+		cfg.Logger.Warnf(ctx, "デッドロックが発生したため、%s後にトランザクションを再試行します (%d/%d)", wait, attempt+1, retries)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// トランザクションを1回実行し、デッドロックが発生したかどうかを返す
+func runInTxOnce(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) (deadlock bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("トランザクションの開始に失敗しました: %w", err)
+	}
+	u := &unitOfWork{tx: tx}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, u)); err != nil || u.deadlock {
+		tx.Rollback()
+		if err == nil {
+			err = errors.New("デッドロックが発生したためロールバックしました")
+		}
+		return u.deadlock, err
+	}
+	if err := tx.Commit(); err != nil {
+		return IsDeadlockError(err), fmt.Errorf("トランザクションのコミットに失敗しました: %w", err)
+	}
+	for _, f := range u.afterCommit {
+		f()
+	}
+	return false, nil
+}
+
+// AfterCommit はコンテキストのトランザクションがコミットされた後に fn を実行する
+// ロールバックした場合や、デッドロックで再試行した回の fn は実行しない (再試行した回で登録した fn のみ実行する)
+// コンテキストにトランザクションがない場合は、変更が既に反映されているため fn をすぐに実行する
+// メトリクスの更新など、コミットした変更の件数のみを数える処理に使用する
+func AfterCommit(ctx context.Context, fn func()) {
+	u, ok := ctx.Value(txKey{}).(*unitOfWork)
+	if !ok {
+		fn()
+		return
+	}
+	u.afterCommit = append(u.afterCommit, fn)
+}
+
+// Savepoint はトランザクション内にセーブポイントを作成して fn を実行する
+// fn がエラーを返した場合はセーブポイントまでロールバックし、トランザクション自体は継続する
+// コンテキストにトランザクションがない場合は RunInTx と同じく新しいトランザクションで実行する
+// name はクエリにそのまま埋め込むため、呼び出し側で固定の識別子を指定すること
+func Savepoint(ctx context.Context, db *sql.DB, name string, fn func(ctx context.Context) error) error {
+	u, ok := ctx.Value(txKey{}).(*unitOfWork)
+	if !ok {
+		return RunInTx(ctx, db, fn)
+	}
+
+	if _, err := u.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("セーブポイントの作成に失敗しました: %w", err)
+	}
+	hooks := len(u.afterCommit)
+	if err := fn(ctx); err != nil {
+		// セーブポイントまでロールバックした変更のコミット後の処理は実行しない
+		u.afterCommit = u.afterCommit[:hooks]
+		// デッドロックの場合はトランザクション全体がロールバックされているため、RunInTx の再試行に任せる
+		if u.deadlock {
+			return err
+		}
+		if _, rbErr := u.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("セーブポイントへのロールバックに失敗しました: %w", rbErr)
+		}
+		return err
+	}
+	if _, err := u.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("セーブポイントの解放に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// TxAborted はコンテキストのトランザクションでデッドロックが発生し、
+// データベース側でロールバックされたかどうかを返す
+func TxAborted(ctx context.Context) bool {
+	u, ok := ctx.Value(txKey{}).(*unitOfWork)
+	return ok && u.deadlock
+}